@@ -0,0 +1,123 @@
+package joystick
+
+import (
+	"math"
+)
+
+// The range of the values in State.AxisData, on every platform.
+// A centered axis reads 0
+const (
+	MinAxisValue = -32767
+	MaxAxisValue = 32767
+)
+
+// ScaleAxis maps a raw axis value from the device range min..max to MinAxisValue..MaxAxisValue.
+// min maps to MinAxisValue, max to MaxAxisValue and the middle of the range to 0, rounding
+// to the nearest value. Values outside the device range are clamped.
+// Values at the same distance either side of the middle map to opposite values
+func ScaleAxis(value, min, max int64) int {
+	if max <= min {
+		return 0
+	}
+	if value < min {
+		value = min
+	}
+	if value > max {
+		value = max
+	}
+	span := max - min
+	// the result is d / span, d being twice the distance from the middle of the range times MaxAxisValue.
+	// Halves are rounded away from zero, so values either side of the middle map symmetrically
+	d := (2*(value-min) - span) * MaxAxisValue
+	if d < 0 {
+		return -int((-2*d + span) / (2 * span))
+	}
+	return int((2*d + span) / (2 * span))
+}
+
+// povAxes converts the angle of a POV hat, in hundredths of a degree clockwise from up,
+// to a pair of horizontal and vertical axis values. Angles above 359 degrees, such as
+// the 0xffff reported by Windows, mean centered
+func povAxes(pov uint32) (x, y int) {
+	angleDeg := float64(pov) / 100.0
+	if angleDeg > 359.0 {
+		return 0, 0
+	}
+	sin, cos := math.Sincos(angleDeg * math.Pi / 180.0)
+	return axisFromPov(sin), axisFromPov(-cos)
+}
+
+func axisFromPov(povVal float64) int {
+	switch {
+	case povVal < -0.5:
+		return MinAxisValue
+	case povVal > 0.5:
+		return MaxAxisValue
+	default:
+		return 0
+	}
+}
+
+// hatAxes converts the position of a HID hat switch, 0 being up and increasing clockwise
+// in eighths of a turn, to a pair of horizontal and vertical axis values.
+// Values outside 0..7 mean centered
+func hatAxes(value int) (x, y int) {
+	if value < 0 || value > 7 {
+		return 0, 0
+	}
+
+	if value == 0 || value == 4 {
+		x = 0
+	} else if value < 4 {
+		x = MaxAxisValue
+	} else {
+		x = MinAxisValue
+	}
+
+	if value == 2 || value == 6 {
+		y = 0
+	} else if value > 2 && value < 6 {
+		y = MaxAxisValue
+	} else {
+		y = MinAxisValue
+	}
+	return x, y
+}
+
+// Axis returns the value of an axis in the range -1 to 1, or 0 if there is no such axis
+func (s State) Axis(axis int) float64 {
+	if axis < 0 || axis >= len(s.AxisData) {
+		return 0
+	}
+	return normalizeAxis(s.AxisData[axis])
+}
+
+// Trigger returns the value of an axis used as a trigger, in the range 0 to 1.
+// The trigger is released at the axis minimum, and fully pulled at its maximum
+func (s State) Trigger(axis int) float64 {
+	if axis < 0 || axis >= len(s.AxisData) {
+		return 0
+	}
+	return normalizeTrigger(s.AxisData[axis])
+}
+
+// normalizeAxis maps MinAxisValue..MaxAxisValue to -1..1
+func normalizeAxis(v int) float64 {
+	return float64(clampAxis(v)) / MaxAxisValue
+}
+
+// normalizeTrigger maps MinAxisValue..MaxAxisValue to 0..1
+func normalizeTrigger(v int) float64 {
+	return float64(clampAxis(v)-MinAxisValue) / (MaxAxisValue - MinAxisValue)
+}
+
+// clampAxis limits v to the range MinAxisValue..MaxAxisValue
+func clampAxis(v int) int {
+	if v > MaxAxisValue {
+		return MaxAxisValue
+	}
+	if v < MinAxisValue {
+		return MinAxisValue
+	}
+	return v
+}
@@ -0,0 +1,141 @@
+package joystick
+
+import (
+	"math"
+	"testing"
+)
+
+func TestScaleAxis(t *testing.T) {
+	tests := []struct {
+		value, min, max int64
+		want            int
+	}{
+		// ends and middle of the range
+		{0, 0, 1024, MinAxisValue},
+		{1024, 0, 1024, MaxAxisValue},
+		{512, 0, 1024, 0},
+		{-100, -100, 100, MinAxisValue},
+		{100, -100, 100, MaxAxisValue},
+		{0, -100, 100, 0},
+		// odd spans have no middle value
+		{0, 0, 255, MinAxisValue},
+		{255, 0, 255, MaxAxisValue},
+		{127, 0, 255, -128},
+		{128, 0, 255, 128},
+		{0, -32768, 32767, 0},
+		{-32768, -32768, 32767, MinAxisValue},
+		{32767, -32768, 32767, MaxAxisValue},
+		// halves round away from zero
+		{50, 0, 200, -16384},
+		{150, 0, 200, 16384},
+		// already in range
+		{-32767, -32767, 32767, -32767},
+		{1234, -32767, 32767, 1234},
+		// smallest spans
+		{0, 0, 1, MinAxisValue},
+		{1, 0, 1, MaxAxisValue},
+		{1, 0, 2, 0},
+		// widest range reported by evdev
+		{math.MinInt32, math.MinInt32, math.MaxInt32, MinAxisValue},
+		{math.MaxInt32, math.MinInt32, math.MaxInt32, MaxAxisValue},
+		{0, math.MinInt32, math.MaxInt32, 0},
+		// out of range values are clamped
+		{-1, 0, 255, MinAxisValue},
+		{1000, 0, 255, MaxAxisValue},
+		// empty or reversed ranges
+		{5, 5, 5, 0},
+		{5, 10, 0, 0},
+	}
+	for _, test := range tests {
+		if got := ScaleAxis(test.value, test.min, test.max); got != test.want {
+			t.Errorf("ScaleAxis(%d, %d, %d) = %d, want %d", test.value, test.min, test.max, got, test.want)
+		}
+	}
+}
+
+func TestScaleAxisSymmetric(t *testing.T) {
+	for _, r := range [][2]int64{{0, 255}, {0, 1024}, {-1, 1}, {0, 200}, {-512, 511}, {0, 65535}, {3, 1000}} {
+		min, max := r[0], r[1]
+		prev := MinAxisValue
+		for v := min; v <= max; v++ {
+			got := ScaleAxis(v, min, max)
+			if mirror := ScaleAxis(max-(v-min), min, max); got != -mirror {
+				t.Fatalf("range %d..%d: %d maps to %d, but %d maps to %d", min, max, v, got, max-(v-min), mirror)
+			}
+			if got < prev {
+				t.Fatalf("range %d..%d: %d maps to %d, below the previous value %d", min, max, v, got, prev)
+			}
+			prev = got
+		}
+	}
+}
+
+func TestPovAxes(t *testing.T) {
+	tests := []struct {
+		pov  uint32
+		x, y int
+	}{
+		{0, 0, MinAxisValue},
+		{4500, MaxAxisValue, MinAxisValue},
+		{9000, MaxAxisValue, 0},
+		{13500, MaxAxisValue, MaxAxisValue},
+		{18000, 0, MaxAxisValue},
+		{22500, MinAxisValue, MaxAxisValue},
+		{27000, MinAxisValue, 0},
+		{31500, MinAxisValue, MinAxisValue},
+		{35900, 0, MinAxisValue},
+		// centered
+		{0xffff, 0, 0},
+		{36000, 0, 0},
+	}
+	for _, test := range tests {
+		if x, y := povAxes(test.pov); x != test.x || y != test.y {
+			t.Errorf("povAxes(%d) = %d, %d, want %d, %d", test.pov, x, y, test.x, test.y)
+		}
+	}
+}
+
+func TestHatAxes(t *testing.T) {
+	tests := []struct {
+		value, x, y int
+	}{
+		{0, 0, MinAxisValue},
+		{1, MaxAxisValue, MinAxisValue},
+		{2, MaxAxisValue, 0},
+		{3, MaxAxisValue, MaxAxisValue},
+		{4, 0, MaxAxisValue},
+		{5, MinAxisValue, MaxAxisValue},
+		{6, MinAxisValue, 0},
+		{7, MinAxisValue, MinAxisValue},
+		// null states
+		{8, 0, 0},
+		{15, 0, 0},
+		{-1, 0, 0},
+	}
+	for _, test := range tests {
+		if x, y := hatAxes(test.value); x != test.x || y != test.y {
+			t.Errorf("hatAxes(%d) = %d, %d, want %d, %d", test.value, x, y, test.x, test.y)
+		}
+	}
+}
+
+func TestStateAxis(t *testing.T) {
+	s := State{AxisData: []int{MinAxisValue, 0, MaxAxisValue, 40000, -40000, 16384}}
+
+	axis := []float64{-1, 0, 1, 1, -1, 16384.0 / 32767}
+	trigger := []float64{0, 0.5, 1, 1, 0, (16384.0 + 32767) / 65534}
+	for i := range s.AxisData {
+		if got := s.Axis(i); got != axis[i] {
+			t.Errorf("Axis(%d) = %v, want %v", i, got, axis[i])
+		}
+		if got := s.Trigger(i); got != trigger[i] {
+			t.Errorf("Trigger(%d) = %v, want %v", i, got, trigger[i])
+		}
+	}
+
+	for _, i := range []int{-1, len(s.AxisData)} {
+		if s.Axis(i) != 0 || s.Trigger(i) != 0 {
+			t.Errorf("got %v and %v for missing axis %d, want 0", s.Axis(i), s.Trigger(i), i)
+		}
+	}
+}
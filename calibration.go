@@ -0,0 +1,109 @@
+package joystick
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// CorrectionType selects how the driver corrects the raw values of an axis
+type CorrectionType uint16
+
+const (
+	// CorrectionNone reports raw values
+	CorrectionNone CorrectionType = 0x00
+	// CorrectionBroken applies a broken line correction, as computed by jscal
+	CorrectionBroken CorrectionType = 0x01
+)
+
+// Correction holds the correction coefficients the driver applies to one axis.
+// The layout matches the linux struct js_corr
+type Correction struct {
+	// For CorrectionBroken: center min, center max, negative gain, positive gain
+	Coef [8]int32
+	// Precision of the raw values
+	Precision int16
+	Type      CorrectionType
+}
+
+// Interface Calibrator is implemented by Joysticks whose driver can correct axis values.
+// Under linux this is the joydev interface, as opened by Open()
+type Calibrator interface {
+	// Correction returns the correction applied to each axis
+	Correction() ([]Correction, error)
+	// SetCorrection sets the correction applied to each axis
+	SetCorrection(corr []Correction) error
+	// ResetCorrection turns correction off, so that every axis reports raw values
+	ResetCorrection() error
+}
+
+// WriteCorrection writes corrections in the format used by "jscal -s":
+//   axes,type,precision,coef0,coef1,coef2,coef3,type,precision,...
+func WriteCorrection(w io.Writer, corr []Correction) error {
+	fields := []string{strconv.Itoa(len(corr))}
+	for _, c := range corr {
+		fields = append(fields, strconv.Itoa(int(c.Type)), strconv.Itoa(int(c.Precision)))
+		for _, coef := range c.Coef[:4] {
+			fields = append(fields, strconv.Itoa(int(coef)))
+		}
+	}
+	_, err := fmt.Fprintln(w, strings.Join(fields, ","))
+	return err
+}
+
+// ReadCorrection reads corrections written by WriteCorrection.
+// The "jscal -s" command line printed by "jscal -p" is accepted too
+func ReadCorrection(r io.Reader) ([]Correction, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return nil, err
+	}
+
+	line, err = correctionValues(line)
+	if err != nil {
+		return nil, err
+	}
+
+	values := []int64{}
+	for _, field := range strings.Split(line, ",") {
+		v, err := strconv.ParseInt(strings.TrimSpace(field), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid correction value %q", field)
+		}
+		values = append(values, v)
+	}
+
+	axes := int(values[0])
+	if axes < 0 || len(values) != 1+axes*6 {
+		return nil, fmt.Errorf("expected %d correction values, got %d", 1+axes*6, len(values))
+	}
+
+	corr := make([]Correction, axes)
+	for i := range corr {
+		v := values[1+i*6:]
+		corr[i].Type = CorrectionType(v[0])
+		corr[i].Precision = int16(v[1])
+		for j := 0; j < 4; j++ {
+			corr[i].Coef[j] = int32(v[2+j])
+		}
+	}
+	return corr, nil
+}
+
+// correctionValues returns the comma separated values of a line written by WriteCorrection,
+// or the argument of -s in a jscal command line such as:
+//   jscal -s 2,1,0,125,125,4329472,4194304,1,0,125,125,4329472,4194304 /dev/input/js0
+func correctionValues(line string) (string, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 1 {
+		return fields[0], nil
+	}
+	for i := 0; i+1 < len(fields); i++ {
+		if fields[i] == "-s" {
+			return fields[i+1], nil
+		}
+	}
+	return "", fmt.Errorf("invalid correction %q", strings.TrimSpace(line))
+}
@@ -0,0 +1,76 @@
+package joystick
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testCorrection = []Correction{
+	{Type: CorrectionBroken, Precision: 0, Coef: [8]int32{125, 125, 4329472, 4194304}},
+	{Type: CorrectionBroken, Precision: 2, Coef: [8]int32{-10, 12, 536854528, 536854528}},
+	{Type: CorrectionNone},
+}
+
+func TestCorrectionRoundTrip(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteCorrection(&b, testCorrection); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "3,1,0,125,125,4329472,4194304,1,2,-10,12,536854528,536854528,0,0,0,0,0,0\n"
+	if b.String() != want {
+		t.Errorf("wrote %q, want %q", b.String(), want)
+	}
+
+	corr, err := ReadCorrection(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(corr, testCorrection) {
+		t.Errorf("read %v, want %v", corr, testCorrection)
+	}
+}
+
+func TestReadCorrection(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []Correction
+	}{
+		{"1,1,0,100,200,300,400", []Correction{{Type: CorrectionBroken, Coef: [8]int32{100, 200, 300, 400}}}},
+		{"0", []Correction{}},
+		// without a trailing newline
+		{"1,0,0,0,0,0,0", []Correction{{}}},
+		// as printed by jscal -p
+		{"jscal -s 1,1,0,100,200,300,400 /dev/input/js0\n", []Correction{{Type: CorrectionBroken, Coef: [8]int32{100, 200, 300, 400}}}},
+		{"  jscal  -s 1,1,0,100,200,300,400\n", []Correction{{Type: CorrectionBroken, Coef: [8]int32{100, 200, 300, 400}}}},
+	}
+	for _, test := range tests {
+		corr, err := ReadCorrection(strings.NewReader(test.input))
+		if err != nil {
+			t.Errorf("ReadCorrection(%q): %v", test.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(corr, test.want) {
+			t.Errorf("ReadCorrection(%q) = %v, want %v", test.input, corr, test.want)
+		}
+	}
+}
+
+func TestReadCorrectionErrors(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"\n",
+		"1,1,0,100,200,300",
+		"2,1,0,100,200,300,400",
+		"-1",
+		"1,1,0,100,200,300,x",
+		"jscal /dev/input/js0",
+		"jscal -s",
+	} {
+		if corr, err := ReadCorrection(strings.NewReader(input)); err == nil {
+			t.Errorf("ReadCorrection(%q) = %v, expected an error", input, corr)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package joystick
+
+import (
+	"fmt"
+)
+
+// AxisCode identifies the function of an axis. The values are the linux ABS_ codes
+type AxisCode uint16
+
+// ButtonCode identifies the function of a button. The values are the linux BTN_ codes
+type ButtonCode uint16
+
+const (
+	AxisX        AxisCode = 0x00
+	AxisY        AxisCode = 0x01
+	AxisZ        AxisCode = 0x02
+	AxisRX       AxisCode = 0x03
+	AxisRY       AxisCode = 0x04
+	AxisRZ       AxisCode = 0x05
+	AxisThrottle AxisCode = 0x06
+	AxisRudder   AxisCode = 0x07
+	AxisWheel    AxisCode = 0x08
+	AxisGas      AxisCode = 0x09
+	AxisBrake    AxisCode = 0x0a
+	AxisHat0X    AxisCode = 0x10
+	AxisHat0Y    AxisCode = 0x11
+	AxisHat1X    AxisCode = 0x12
+	AxisHat1Y    AxisCode = 0x13
+	AxisHat2X    AxisCode = 0x14
+	AxisHat2Y    AxisCode = 0x15
+	AxisHat3X    AxisCode = 0x16
+	AxisHat3Y    AxisCode = 0x17
+	AxisPressure AxisCode = 0x18
+	AxisDistance AxisCode = 0x19
+	AxisTiltX    AxisCode = 0x1a
+	AxisTiltY    AxisCode = 0x1b
+	AxisVolume   AxisCode = 0x20
+	AxisMisc     AxisCode = 0x28
+)
+
+const (
+	Button0       ButtonCode = 0x100
+	Button1       ButtonCode = 0x101
+	Button2       ButtonCode = 0x102
+	Button3       ButtonCode = 0x103
+	Button4       ButtonCode = 0x104
+	Button5       ButtonCode = 0x105
+	Button6       ButtonCode = 0x106
+	Button7       ButtonCode = 0x107
+	Button8       ButtonCode = 0x108
+	Button9       ButtonCode = 0x109
+	ButtonLeft    ButtonCode = 0x110
+	ButtonRight   ButtonCode = 0x111
+	ButtonMiddle  ButtonCode = 0x112
+	ButtonSide    ButtonCode = 0x113
+	ButtonExtra   ButtonCode = 0x114
+	ButtonTrigger ButtonCode = 0x120
+	ButtonThumb   ButtonCode = 0x121
+	ButtonThumb2  ButtonCode = 0x122
+	ButtonTop     ButtonCode = 0x123
+	ButtonTop2    ButtonCode = 0x124
+	ButtonPinkie  ButtonCode = 0x125
+	ButtonBase    ButtonCode = 0x126
+	ButtonBase2   ButtonCode = 0x127
+	ButtonBase3   ButtonCode = 0x128
+	ButtonBase4   ButtonCode = 0x129
+	ButtonBase5   ButtonCode = 0x12a
+	ButtonBase6   ButtonCode = 0x12b
+	ButtonDead    ButtonCode = 0x12f
+	ButtonSouth   ButtonCode = 0x130
+	ButtonEast    ButtonCode = 0x131
+	ButtonC       ButtonCode = 0x132
+	ButtonNorth   ButtonCode = 0x133
+	ButtonWest    ButtonCode = 0x134
+	ButtonZ       ButtonCode = 0x135
+	ButtonTL      ButtonCode = 0x136
+	ButtonTR      ButtonCode = 0x137
+	ButtonTL2     ButtonCode = 0x138
+	ButtonTR2     ButtonCode = 0x139
+	ButtonSelect  ButtonCode = 0x13a
+	ButtonStart   ButtonCode = 0x13b
+	ButtonMode    ButtonCode = 0x13c
+	ButtonThumbL  ButtonCode = 0x13d
+	ButtonThumbR  ButtonCode = 0x13e
+
+	ButtonDpadUp    ButtonCode = 0x220
+	ButtonDpadDown  ButtonCode = 0x221
+	ButtonDpadLeft  ButtonCode = 0x222
+	ButtonDpadRight ButtonCode = 0x223
+
+	// ButtonTriggerHappy1 is the first of 40 generic buttons, up to ButtonTriggerHappy1+39
+	ButtonTriggerHappy1 ButtonCode = 0x2c0
+)
+
+var axisNames = map[AxisCode]string{
+	AxisX: "ABS_X", AxisY: "ABS_Y", AxisZ: "ABS_Z",
+	AxisRX: "ABS_RX", AxisRY: "ABS_RY", AxisRZ: "ABS_RZ",
+	AxisThrottle: "ABS_THROTTLE", AxisRudder: "ABS_RUDDER", AxisWheel: "ABS_WHEEL",
+	AxisGas: "ABS_GAS", AxisBrake: "ABS_BRAKE",
+	AxisHat0X: "ABS_HAT0X", AxisHat0Y: "ABS_HAT0Y", AxisHat1X: "ABS_HAT1X", AxisHat1Y: "ABS_HAT1Y",
+	AxisHat2X: "ABS_HAT2X", AxisHat2Y: "ABS_HAT2Y", AxisHat3X: "ABS_HAT3X", AxisHat3Y: "ABS_HAT3Y",
+	AxisPressure: "ABS_PRESSURE", AxisDistance: "ABS_DISTANCE",
+	AxisTiltX: "ABS_TILT_X", AxisTiltY: "ABS_TILT_Y",
+	AxisVolume: "ABS_VOLUME", AxisMisc: "ABS_MISC",
+}
+
+var buttonNames = map[ButtonCode]string{
+	Button0: "BTN_0", Button1: "BTN_1", Button2: "BTN_2", Button3: "BTN_3", Button4: "BTN_4",
+	Button5: "BTN_5", Button6: "BTN_6", Button7: "BTN_7", Button8: "BTN_8", Button9: "BTN_9",
+	ButtonLeft: "BTN_LEFT", ButtonRight: "BTN_RIGHT", ButtonMiddle: "BTN_MIDDLE",
+	ButtonSide: "BTN_SIDE", ButtonExtra: "BTN_EXTRA",
+	ButtonTrigger: "BTN_TRIGGER", ButtonThumb: "BTN_THUMB", ButtonThumb2: "BTN_THUMB2",
+	ButtonTop: "BTN_TOP", ButtonTop2: "BTN_TOP2", ButtonPinkie: "BTN_PINKIE",
+	ButtonBase: "BTN_BASE", ButtonBase2: "BTN_BASE2", ButtonBase3: "BTN_BASE3",
+	ButtonBase4: "BTN_BASE4", ButtonBase5: "BTN_BASE5", ButtonBase6: "BTN_BASE6", ButtonDead: "BTN_DEAD",
+	ButtonSouth: "BTN_SOUTH", ButtonEast: "BTN_EAST", ButtonC: "BTN_C",
+	ButtonNorth: "BTN_NORTH", ButtonWest: "BTN_WEST", ButtonZ: "BTN_Z",
+	ButtonTL: "BTN_TL", ButtonTR: "BTN_TR", ButtonTL2: "BTN_TL2", ButtonTR2: "BTN_TR2",
+	ButtonSelect: "BTN_SELECT", ButtonStart: "BTN_START", ButtonMode: "BTN_MODE",
+	ButtonThumbL: "BTN_THUMBL", ButtonThumbR: "BTN_THUMBR",
+	ButtonDpadUp: "BTN_DPAD_UP", ButtonDpadDown: "BTN_DPAD_DOWN",
+	ButtonDpadLeft: "BTN_DPAD_LEFT", ButtonDpadRight: "BTN_DPAD_RIGHT",
+}
+
+func (c AxisCode) String() string {
+	if name, ok := axisNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("ABS_0x%02x", uint16(c))
+}
+
+func (c ButtonCode) String() string {
+	if name, ok := buttonNames[c]; ok {
+		return name
+	}
+	if c >= ButtonTriggerHappy1 && c < ButtonTriggerHappy1+40 {
+		return fmt.Sprintf("BTN_TRIGGER_HAPPY%d", c-ButtonTriggerHappy1+1)
+	}
+	return fmt.Sprintf("BTN_0x%03x", uint16(c))
+}
+
+// Interface CodeMapper is implemented by Joysticks that know the function of each axis and button
+type CodeMapper interface {
+	// AxisCodes returns the code of each axis, in the same order as State.AxisData
+	AxisCodes() []AxisCode
+	// ButtonCodes returns the code of each button, in the same order as State.ButtonData
+	ButtonCodes() []ButtonCode
+}
+
+// FindAxis returns the index of the axis with the supplied code,
+// or -1 if the joystick has no such axis or does not implement CodeMapper
+func FindAxis(js Joystick, code AxisCode) int {
+	if m, ok := js.(CodeMapper); ok {
+		for i, c := range m.AxisCodes() {
+			if c == code {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// FindButton returns the index of the button with the supplied code,
+// or -1 if the joystick has no such button or does not implement CodeMapper
+func FindButton(js Joystick, code ButtonCode) int {
+	if m, ok := js.(CodeMapper); ok {
+		for i, c := range m.ButtonCodes() {
+			if c == code {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,221 @@
+package joystick
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// Curve changes the response of an axis
+type Curve interface {
+	// Map maps an axis value in the range -32767..32767 to a new value in the same range
+	Map(v int) int
+}
+
+// LinearCurve leaves values unchanged
+type LinearCurve struct{}
+
+func (LinearCurve) Map(v int) int {
+	return clampAxis(v)
+}
+
+// ExponentialCurve raises the distance from center to a power. Exponents above 1
+// give finer control near center, below 1 coarser control. Exponents that are not
+// positive and finite, such as 0, are treated as 1
+type ExponentialCurve struct {
+	Exponent float64
+}
+
+func (c ExponentialCurve) Map(v int) int {
+	if !validExponent(c.Exponent) || c.Exponent == 0 || c.Exponent == 1 {
+		return clampAxis(v)
+	}
+	return mapNormalized(v, func(x float64) float64 {
+		return math.Pow(x, c.Exponent)
+	})
+}
+
+// SCurve blends a linear response with a cubic one: Strength 0 is linear, 1 fully cubic.
+// The response is flattened near center and steepened towards the ends.
+// Strengths outside 0..1 are limited to that range
+type SCurve struct {
+	Strength float64
+}
+
+func (c SCurve) Map(v int) int {
+	if !(c.Strength > 0) {
+		return clampAxis(v)
+	}
+	k := math.Min(1, c.Strength)
+	return mapNormalized(v, func(x float64) float64 {
+		return (1-k)*x + k*x*x*x
+	})
+}
+
+// validExponent returns true for the exponents of an ExponentialCurve: 0, which means 1,
+// or a finite positive number
+func validExponent(e float64) bool {
+	return e >= 0 && !math.IsInf(e, 1)
+}
+
+// mapNormalized applies f, defined on 0..1, symmetrically to both halves of the axis
+func mapNormalized(v int, f func(float64) float64) int {
+	v = clampAxis(v)
+	x := math.Abs(float64(v)) / 32767
+	out := int(math.Round(f(x) * 32767))
+	if v < 0 {
+		out = -out
+	}
+	return clampAxis(out)
+}
+
+// CurvePoint is a point of a PiecewiseCurve
+type CurvePoint struct {
+	In, Out int
+}
+
+// PiecewiseCurve interpolates linearly between points. Values outside the points take
+// the output of the nearest end point. If no point has a negative input, the curve is
+// mirrored for negative values. Points must be sorted by input
+type PiecewiseCurve struct {
+	Points []CurvePoint
+}
+
+// NewPiecewiseCurve returns a PiecewiseCurve with its points sorted by input
+func NewPiecewiseCurve(points ...CurvePoint) (PiecewiseCurve, error) {
+	if len(points) < 2 {
+		return PiecewiseCurve{}, fmt.Errorf("piecewise curve needs at least 2 points, got %d", len(points))
+	}
+	p := append([]CurvePoint(nil), points...)
+	sort.Slice(p, func(i, j int) bool { return p[i].In < p[j].In })
+	for i := 1; i < len(p); i++ {
+		if p[i].In == p[i-1].In {
+			return PiecewiseCurve{}, fmt.Errorf("piecewise curve has two points with input %d", p[i].In)
+		}
+	}
+	return PiecewiseCurve{p}, nil
+}
+
+func (c PiecewiseCurve) Map(v int) int {
+	p := c.Points
+	if len(p) == 0 {
+		return clampAxis(v)
+	}
+
+	v = clampAxis(v)
+	if v < 0 && p[0].In >= 0 {
+		return -c.Map(-v)
+	}
+
+	if v <= p[0].In {
+		return clampAxis(p[0].Out)
+	}
+	for i := 1; i < len(p); i++ {
+		if v <= p[i].In {
+			a, b := p[i-1], p[i]
+			// integer interpolation so that points, and identity tables, are reproduced exactly
+			out := int64(a.Out) + int64(v-a.In)*int64(b.Out-a.Out)/int64(b.In-a.In)
+			return clampAxis(int(out))
+		}
+	}
+	return clampAxis(p[len(p)-1].Out)
+}
+
+// AxisCurve returns a Filter that applies a curve to an axis
+func AxisCurve(axis int, c Curve) Filter {
+	return FilterFunc(func(s *State) {
+		if axis < 0 || axis >= len(s.AxisData) {
+			return
+		}
+		s.AxisData[axis] = c.Map(s.AxisData[axis])
+	})
+}
+
+// CurveConfig describes the curve of an axis in a profile
+type CurveConfig struct {
+	Axis int `json:"axis"`
+	// one of "linear", "exponential", "s-curve" or "piecewise"
+	Type     string  `json:"type"`
+	Exponent float64 `json:"exponent,omitempty"`
+	Strength float64 `json:"strength,omitempty"`
+	// input, output pairs for piecewise curves
+	Points [][2]int `json:"points,omitempty"`
+}
+
+// Curve returns the curve described by the config
+func (c CurveConfig) Curve() (Curve, error) {
+	switch c.Type {
+	case "linear", "":
+		return LinearCurve{}, nil
+	case "exponential":
+		if !validExponent(c.Exponent) {
+			return nil, fmt.Errorf("invalid exponent %v, must be positive", c.Exponent)
+		}
+		return ExponentialCurve{c.Exponent}, nil
+	case "s-curve":
+		if !(c.Strength >= 0 && c.Strength <= 1) {
+			return nil, fmt.Errorf("invalid strength %v, must be between 0 and 1", c.Strength)
+		}
+		return SCurve{c.Strength}, nil
+	case "piecewise":
+		points := make([]CurvePoint, len(c.Points))
+		for i, p := range c.Points {
+			points[i] = CurvePoint{p[0], p[1]}
+		}
+		return NewPiecewiseCurve(points...)
+	}
+	return nil, fmt.Errorf("unknown curve type %q", c.Type)
+}
+
+// Profile is a set of axis curves, usually read from a config file:
+//   {
+//     "name": "flight",
+//     "curves": [
+//       {"axis": 0, "type": "exponential", "exponent": 2},
+//       {"axis": 1, "type": "s-curve", "strength": 0.5},
+//       {"axis": 2, "type": "piecewise", "points": [[0, 0], [16384, 8192], [32767, 32767]]}
+//     ]
+//   }
+type Profile struct {
+	Name   string        `json:"name,omitempty"`
+	Curves []CurveConfig `json:"curves"`
+}
+
+// ReadProfile reads a profile in JSON format
+func ReadProfile(r io.Reader) (*Profile, error) {
+	var p Profile
+	if err := json.NewDecoder(r).Decode(&p); err != nil {
+		return nil, err
+	}
+	if _, err := p.Filter(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// WriteProfile writes a profile in JSON format
+func WriteProfile(w io.Writer, p *Profile) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(p)
+}
+
+// Filter returns a Filter that applies the curves of the profile
+func (p *Profile) Filter() (Filter, error) {
+	filters := make([]Filter, len(p.Curves))
+	for i, c := range p.Curves {
+		curve, err := c.Curve()
+		if err != nil {
+			return nil, fmt.Errorf("axis %d: %v", c.Axis, err)
+		}
+		filters[i] = AxisCurve(c.Axis, curve)
+	}
+
+	return FilterFunc(func(s *State) {
+		for _, f := range filters {
+			f.Apply(s)
+		}
+	}), nil
+}
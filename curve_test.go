@@ -0,0 +1,208 @@
+package joystick
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// checkIdentity checks that c maps every axis value to itself
+func checkIdentity(t *testing.T, name string, c Curve) {
+	t.Helper()
+	for v := MinAxisValue; v <= MaxAxisValue; v++ {
+		if got := c.Map(v); got != v {
+			t.Errorf("%s: Map(%d) = %d", name, v, got)
+			return
+		}
+	}
+}
+
+func mustPiecewise(t *testing.T, points ...CurvePoint) PiecewiseCurve {
+	t.Helper()
+	c, err := NewPiecewiseCurve(points...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCurveIdentity(t *testing.T) {
+	checkIdentity(t, "linear", LinearCurve{})
+	checkIdentity(t, "exponent 1", ExponentialCurve{1})
+	checkIdentity(t, "exponent 0", ExponentialCurve{0})
+	checkIdentity(t, "s-curve 0", SCurve{0})
+	checkIdentity(t, "piecewise", mustPiecewise(t, CurvePoint{MinAxisValue, MinAxisValue}, CurvePoint{MaxAxisValue, MaxAxisValue}))
+	checkIdentity(t, "mirrored piecewise", mustPiecewise(t, CurvePoint{0, 0}, CurvePoint{MaxAxisValue, MaxAxisValue}))
+	checkIdentity(t, "piecewise with inner points", mustPiecewise(t,
+		CurvePoint{MaxAxisValue, MaxAxisValue}, CurvePoint{-1000, -1000}, CurvePoint{0, 0}, CurvePoint{12345, 12345}, CurvePoint{MinAxisValue, MinAxisValue}))
+	checkIdentity(t, "empty piecewise", PiecewiseCurve{})
+
+	// out of range values are clamped
+	var linear LinearCurve
+	if linear.Map(40000) != MaxAxisValue || linear.Map(-40000) != MinAxisValue {
+		t.Error("linear curve does not clamp")
+	}
+}
+
+func TestPiecewiseCurve(t *testing.T) {
+	c := mustPiecewise(t, CurvePoint{32767, 32767}, CurvePoint{0, 0}, CurvePoint{16384, 8192})
+
+	tests := []struct{ in, out int }{
+		// points are reproduced exactly
+		{0, 0}, {16384, 8192}, {32767, 32767},
+		// mirrored for negative values
+		{-16384, -8192}, {-32767, -32767},
+		// interpolated
+		{8192, 4096}, {1, 0}, {-8192, -4096}, {24575, 20478},
+		{40000, 32767},
+	}
+	for _, test := range tests {
+		if got := c.Map(test.in); got != test.out {
+			t.Errorf("Map(%d) = %d, want %d", test.in, got, test.out)
+		}
+	}
+
+	// values outside the points take the output of the nearest point
+	c = mustPiecewise(t, CurvePoint{1000, 0}, CurvePoint{30000, 32767})
+	for in, out := range map[int]int{0: 0, 500: 0, 1000: 0, -500: 0, 31000: 32767, -31000: -32767} {
+		if got := c.Map(in); got != out {
+			t.Errorf("Map(%d) = %d, want %d", in, got, out)
+		}
+	}
+
+	// not mirrored when a point has a negative input
+	c = mustPiecewise(t, CurvePoint{-32767, 0}, CurvePoint{32767, 32767})
+	for in, out := range map[int]int{-32767: 0, 0: 16383, 32767: 32767} {
+		if got := c.Map(in); got != out {
+			t.Errorf("Map(%d) = %d, want %d", in, got, out)
+		}
+	}
+
+	if _, err := NewPiecewiseCurve(CurvePoint{0, 0}); err == nil {
+		t.Error("expected an error for a single point")
+	}
+	if _, err := NewPiecewiseCurve(CurvePoint{0, 0}, CurvePoint{10, 5}, CurvePoint{10, 6}); err == nil {
+		t.Error("expected an error for two points with the same input")
+	}
+}
+
+func TestExponentialAndSCurve(t *testing.T) {
+	tests := []struct {
+		c       Curve
+		in, out int
+	}{
+		{ExponentialCurve{2}, 0, 0},
+		{ExponentialCurve{2}, 16384, 8192},
+		{ExponentialCurve{2}, -16384, -8192},
+		{ExponentialCurve{2}, 32767, 32767},
+		{ExponentialCurve{2}, -32767, -32767},
+		{ExponentialCurve{0.5}, 16384, 23170},
+		// invalid exponents are treated as 1
+		{ExponentialCurve{-2}, 0, 0},
+		{ExponentialCurve{-2}, 100, 100},
+		{ExponentialCurve{math.NaN()}, 100, 100},
+		{ExponentialCurve{math.Inf(1)}, 100, 100},
+		{SCurve{1}, 16384, 4096},
+		{SCurve{0.5}, 16384, 10240},
+		{SCurve{0.5}, -16384, -10240},
+		{SCurve{0.5}, 32767, 32767},
+		{SCurve{2}, 16384, 4096},
+		{SCurve{-1}, 100, 100},
+		{SCurve{math.NaN()}, 100, 100},
+	}
+	for _, test := range tests {
+		if got := test.c.Map(test.in); got != test.out {
+			t.Errorf("%#v.Map(%d) = %d, want %d", test.c, test.in, got, test.out)
+		}
+	}
+}
+
+func TestCurveConfig(t *testing.T) {
+	valid := []CurveConfig{
+		{Type: ""},
+		{Type: "linear"},
+		{Type: "exponential"},
+		{Type: "exponential", Exponent: 2.5},
+		{Type: "s-curve", Strength: 0},
+		{Type: "s-curve", Strength: 1},
+		{Type: "piecewise", Points: [][2]int{{0, 0}, {32767, 32767}}},
+	}
+	for _, c := range valid {
+		if _, err := c.Curve(); err != nil {
+			t.Errorf("%+v: %v", c, err)
+		}
+	}
+
+	invalid := []CurveConfig{
+		{Type: "cubic"},
+		{Type: "exponential", Exponent: -2},
+		{Type: "exponential", Exponent: math.NaN()},
+		{Type: "exponential", Exponent: math.Inf(1)},
+		{Type: "s-curve", Strength: -0.1},
+		{Type: "s-curve", Strength: 1.5},
+		{Type: "s-curve", Strength: math.NaN()},
+		{Type: "piecewise", Points: [][2]int{{0, 0}}},
+	}
+	for _, c := range invalid {
+		if curve, err := c.Curve(); err == nil {
+			t.Errorf("%+v: got %#v, expected an error", c, curve)
+		}
+	}
+}
+
+func TestProfileRoundTrip(t *testing.T) {
+	p := &Profile{
+		Name: "flight",
+		Curves: []CurveConfig{
+			{Axis: 0, Type: "exponential", Exponent: 2},
+			{Axis: 1, Type: "s-curve", Strength: 0.5},
+			{Axis: 2, Type: "piecewise", Points: [][2]int{{0, 0}, {16384, 8192}, {32767, 32767}}},
+			{Axis: 3, Type: "linear"},
+		},
+	}
+
+	var b bytes.Buffer
+	if err := WriteProfile(&b, p); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadProfile(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, p) {
+		t.Errorf("read %+v, want %+v", read, p)
+	}
+
+	f, err := read.Filter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := applyFilter(f, 16384, -16384, -16384, 1234, 16384)
+	if want := []int{8192, -10240, -8192, 1234, 16384}; !reflect.DeepEqual(got, want) {
+		t.Errorf("profile applied = %v, want %v", got, want)
+	}
+
+	// the profile filter reproduces every integer through an identity piecewise curve read back from JSON
+	read, err = ReadProfile(strings.NewReader(`{"curves": [{"axis": 0, "type": "piecewise", "points": [[-32767, -32767], [0, 0], [32767, 32767]]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	curve, _ := read.Curves[0].Curve()
+	checkIdentity(t, "piecewise from JSON", curve)
+}
+
+func TestReadProfileErrors(t *testing.T) {
+	for _, input := range []string{
+		`{"curves": [{"axis": 0, "type": "exponential", "exponent": -2}]}`,
+		`{"curves": [{"axis": 0, "type": "s-curve", "strength": 2}]}`,
+		`{"curves": [{"axis": 0, "type": "spline"}]}`,
+		`{"curves": [{"axis": 0, "type": "piecewise", "points": [[0, 0], [0, 10]]}]}`,
+		`{"curves": `,
+	} {
+		if p, err := ReadProfile(strings.NewReader(input)); err == nil {
+			t.Errorf("ReadProfile(%s) = %+v, expected an error", input, p)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package joystick
+
+import (
+	"math"
+)
+
+// DeadZoneMode selects how a stick dead zone is shaped
+type DeadZoneMode int
+
+const (
+	// Axial applies the dead zone to each axis separately, giving a cross shaped dead zone
+	Axial DeadZoneMode = iota
+	// Radial applies the dead zone to the distance of the stick from center, giving a round dead zone
+	Radial
+)
+
+// DeadZone describes the dead zones of an axis, or of a stick
+type DeadZone struct {
+	Mode DeadZoneMode
+	// Values closer to center than Inner are reported as centered
+	Inner int
+	// Values further from center than Outer are reported as fully deflected.
+	// 0 means no outer dead zone
+	Outer int
+	// Scaled rescales values between Inner and Outer so the full output range is used.
+	// Without it values are reported unchanged
+	Scaled bool
+}
+
+// limits returns the inner and outer limits with defaults applied
+func (dz DeadZone) limits() (inner, outer float64) {
+	inner, outer = float64(dz.Inner), float64(dz.Outer)
+	if outer <= 0 || outer > 32767 {
+		outer = 32767
+	}
+	if inner < 0 {
+		inner = 0
+	}
+	if inner > outer {
+		inner = outer
+	}
+	return inner, outer
+}
+
+// apply maps a distance from center through the dead zone
+func (dz DeadZone) apply(dist float64) float64 {
+	inner, outer := dz.limits()
+	switch {
+	case dist < inner:
+		return 0
+	case dist >= outer:
+		return 32767
+	case dz.Scaled:
+		return (dist - inner) * 32767 / (outer - inner)
+	}
+	return dist
+}
+
+// AxisDeadZone returns a Filter that applies a dead zone to a single axis. Mode is ignored
+func AxisDeadZone(axis int, dz DeadZone) Filter {
+	return FilterFunc(func(s *State) {
+		if axis < 0 || axis >= len(s.AxisData) {
+			return
+		}
+		s.AxisData[axis] = dz.applyAxis(s.AxisData[axis])
+	})
+}
+
+func (dz DeadZone) applyAxis(v int) int {
+	out := int(math.Round(dz.apply(math.Abs(float64(v)))))
+	if v < 0 {
+		out = -out
+	}
+	return out
+}
+
+// StickDeadZone returns a Filter that applies a dead zone to the stick formed by axis x and y
+func StickDeadZone(x, y int, dz DeadZone) Filter {
+	return FilterFunc(func(s *State) {
+		if x < 0 || x >= len(s.AxisData) || y < 0 || y >= len(s.AxisData) {
+			return
+		}
+
+		if dz.Mode == Axial {
+			s.AxisData[x] = dz.applyAxis(s.AxisData[x])
+			s.AxisData[y] = dz.applyAxis(s.AxisData[y])
+			return
+		}
+
+		vx, vy := float64(s.AxisData[x]), float64(s.AxisData[y])
+		dist := math.Hypot(vx, vy)
+		if dist == 0 {
+			return
+		}
+		scale := dz.apply(dist) / dist
+		s.AxisData[x] = clampAxis(int(math.Round(vx * scale)))
+		s.AxisData[y] = clampAxis(int(math.Round(vy * scale)))
+	})
+}
@@ -0,0 +1,91 @@
+package joystick
+
+import (
+	"reflect"
+	"testing"
+)
+
+func applyFilter(f Filter, axes ...int) []int {
+	s := State{AxisData: append([]int(nil), axes...)}
+	f.Apply(&s)
+	return s.AxisData
+}
+
+func TestAxisDeadZone(t *testing.T) {
+	tests := []struct {
+		dz      DeadZone
+		in, out int
+	}{
+		{DeadZone{Inner: 1000}, 0, 0},
+		{DeadZone{Inner: 1000}, 999, 0},
+		{DeadZone{Inner: 1000}, -999, 0},
+		{DeadZone{Inner: 1000}, 1000, 1000},
+		{DeadZone{Inner: 1000}, -20000, -20000},
+		{DeadZone{Inner: 1000}, 32767, 32767},
+		{DeadZone{Inner: 1000, Outer: 30000}, 29999, 29999},
+		{DeadZone{Inner: 1000, Outer: 30000}, 30000, 32767},
+		{DeadZone{Inner: 1000, Outer: 30000}, -31000, -32767},
+
+		// scaled: Inner maps to 0 and Outer to full deflection
+		{DeadZone{Inner: 1000, Outer: 31000, Scaled: true}, 1000, 0},
+		{DeadZone{Inner: 1000, Outer: 31000, Scaled: true}, 16000, 16384},
+		{DeadZone{Inner: 1000, Outer: 31000, Scaled: true}, -16000, -16384},
+		{DeadZone{Inner: 1000, Outer: 31000, Scaled: true}, 31000, 32767},
+		{DeadZone{Inner: 1000, Outer: 31000, Scaled: true}, -32767, -32767},
+		{DeadZone{Inner: 0, Scaled: true}, 12345, 12345},
+
+		// out of range limits
+		{DeadZone{Inner: -5}, 1, 1},
+		{DeadZone{Inner: 40000}, 32766, 0},
+		{DeadZone{Inner: 40000}, 32767, 32767},
+		{DeadZone{Inner: 1000, Outer: 500}, 499, 0},
+		{DeadZone{Inner: 1000, Outer: 500}, 500, 32767},
+	}
+	for _, test := range tests {
+		if got := applyFilter(AxisDeadZone(1, test.dz), 7, test.in); !reflect.DeepEqual(got, []int{7, test.out}) {
+			t.Errorf("%+v applied to %d = %v, want [7 %d]", test.dz, test.in, got, test.out)
+		}
+	}
+
+	// axis that do not exist are ignored
+	if got := applyFilter(AxisDeadZone(2, DeadZone{Inner: 1000}), 10, 20); !reflect.DeepEqual(got, []int{10, 20}) {
+		t.Errorf("got %v for a missing axis", got)
+	}
+}
+
+func TestStickDeadZone(t *testing.T) {
+	tests := []struct {
+		dz      DeadZone
+		in, out [2]int
+	}{
+		// axial: a cross shaped dead zone, each axis independent
+		{DeadZone{Mode: Axial, Inner: 1000}, [2]int{500, 20000}, [2]int{0, 20000}},
+		{DeadZone{Mode: Axial, Inner: 1000}, [2]int{800, -800}, [2]int{0, 0}},
+		{DeadZone{Mode: Axial, Inner: 1000, Outer: 30000, Scaled: true}, [2]int{-30000, 500}, [2]int{-32767, 0}},
+
+		// radial: a round dead zone on the distance from center
+		{DeadZone{Mode: Radial, Inner: 1000}, [2]int{500, 20000}, [2]int{500, 20000}},
+		{DeadZone{Mode: Radial, Inner: 1001}, [2]int{600, -800}, [2]int{0, 0}},
+		{DeadZone{Mode: Radial, Inner: 1000}, [2]int{600, -800}, [2]int{600, -800}},
+		{DeadZone{Mode: Radial, Inner: 1000}, [2]int{0, 0}, [2]int{0, 0}},
+		// the direction is kept when the distance is clamped to the outer edge
+		{DeadZone{Mode: Radial}, [2]int{30000, 30000}, [2]int{23170, 23170}},
+		{DeadZone{Mode: Radial, Outer: 20000}, [2]int{-12000, 16000}, [2]int{-19660, 26214}},
+
+		// scaled radial
+		{DeadZone{Mode: Radial, Inner: 1000, Outer: 11000, Scaled: true}, [2]int{3000, 4000}, [2]int{7864, 10485}},
+		{DeadZone{Mode: Radial, Inner: 1000, Outer: 11000, Scaled: true}, [2]int{-3000, -4000}, [2]int{-7864, -10485}},
+		{DeadZone{Mode: Radial, Inner: 1000, Outer: 11000, Scaled: true}, [2]int{0, -1000}, [2]int{0, 0}},
+		{DeadZone{Mode: Radial, Inner: 1000, Outer: 11000, Scaled: true}, [2]int{0, 11000}, [2]int{0, 32767}},
+	}
+	for _, test := range tests {
+		got := applyFilter(StickDeadZone(2, 0, test.dz), test.in[1], 7, test.in[0])
+		if want := []int{test.out[1], 7, test.out[0]}; !reflect.DeepEqual(got, want) {
+			t.Errorf("%+v applied to %v = %v, want %v", test.dz, test.in, got, want)
+		}
+	}
+
+	if got := applyFilter(StickDeadZone(0, 2, DeadZone{Inner: 1000}), 10, 20); !reflect.DeepEqual(got, []int{10, 20}) {
+		t.Errorf("got %v for a missing axis", got)
+	}
+}
@@ -0,0 +1,141 @@
+// +build linux
+
+package joystick
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// Locations of the device nodes and of their sysfs entries. Tests point these at a fake tree.
+var (
+	devInputRoot = "/dev/input"
+	sysInputRoot = "/sys/class/input"
+)
+
+// Enumerate returns information on every joystick currently attached, sorted by id.
+//
+// Under linux each /dev/input/jsN device is described using sysfs and, when the device
+// can be opened, the same ioctls used by Open(). Devices that cannot be opened,
+// for example due to permissions, are still listed using the information available in sysfs.
+func Enumerate() ([]DeviceInfo, error) {
+	entries, err := os.ReadDir(devInputRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	devices := []DeviceInfo{}
+	for _, entry := range entries {
+		if id, ok := parseNodeID(entry.Name(), "js"); ok {
+			devices = append(devices, deviceInfo(id))
+		}
+	}
+
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].ID < devices[j].ID
+	})
+
+	return devices, nil
+}
+
+// deviceInfo collects the information on joystick id
+func deviceInfo(id int) DeviceInfo {
+	node := fmt.Sprintf("js%d", id)
+	info := DeviceInfo{
+		ID:   id,
+		Path: filepath.Join(devInputRoot, node),
+	}
+
+	readSysfsInfo(&info, filepath.Join(sysInputRoot, node, "device"))
+
+	f, err := os.OpenFile(info.Path, os.O_RDONLY, 0666)
+	if err == nil {
+		if jsinfo, err := queryJoydev(f); err == nil {
+			info.Name = jsinfo.name
+			info.AxisCount = jsinfo.axisCount
+			info.ButtonCount = jsinfo.buttonCount
+			info.DriverVersion = jsinfo.version
+		}
+		f.Close()
+	}
+
+	return info
+}
+
+// readSysfsInfo fills info from the sysfs directory of the input device
+func readSysfsInfo(info *DeviceInfo, dir string) {
+	info.Name = readSysfsString(filepath.Join(dir, "name"))
+	info.Serial = readSysfsString(filepath.Join(dir, "uniq"))
+	info.BusType = readSysfsHex(filepath.Join(dir, "id", "bustype"))
+	info.Vendor = readSysfsHex(filepath.Join(dir, "id", "vendor"))
+	info.Product = readSysfsHex(filepath.Join(dir, "id", "product"))
+	info.Version = readSysfsHex(filepath.Join(dir, "id", "version"))
+
+	// joydev reports every absolute axis, and every key from BTN_MISC upwards as a button
+	info.AxisCount = countSysfsBits(readSysfsString(filepath.Join(dir, "capabilities", "abs")), 0)
+	info.ButtonCount = countSysfsBits(readSysfsString(filepath.Join(dir, "capabilities", "key")), _BTN_MISC)
+
+	if entries, err := os.ReadDir(dir); err == nil {
+		for _, entry := range entries {
+			if _, ok := parseNodeID(entry.Name(), "event"); ok {
+				info.EventPath = filepath.Join(devInputRoot, entry.Name())
+				break
+			}
+		}
+	}
+}
+
+func readSysfsString(path string) string {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+func readSysfsHex(path string) uint16 {
+	v, err := strconv.ParseUint(readSysfsString(path), 16, 16)
+	if err != nil {
+		return 0
+	}
+	return uint16(v)
+}
+
+// countSysfsBits counts the bits set at or above from in a sysfs capability bitmap.
+// The bitmap is a list of hex words, most significant first.
+func countSysfsBits(bitmap string, from int) int {
+	const wordBits = int(unsafe.Sizeof(uintptr(0))) * 8
+
+	words := strings.Fields(bitmap)
+	count := 0
+	for i, word := range words {
+		v, err := strconv.ParseUint(word, 16, wordBits)
+		if err != nil {
+			return 0
+		}
+		base := (len(words) - 1 - i) * wordBits
+		for bit := 0; bit < wordBits; bit++ {
+			if v&(1<<uint(bit)) != 0 && base+bit >= from {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// parseNodeID extracts N from a device node name of the form prefixN
+func parseNodeID(name, prefix string) (int, bool) {
+	if !strings.HasPrefix(name, prefix) {
+		return 0, false
+	}
+	id, err := strconv.Atoi(name[len(prefix):])
+	if err != nil || id < 0 {
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,129 @@
+// +build linux
+
+package joystick
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+// fakeInputTree points devInputRoot and sysInputRoot at empty temporary directories
+func fakeInputTree(t *testing.T) (dev, sys string) {
+	oldDev, oldSys := devInputRoot, sysInputRoot
+	t.Cleanup(func() {
+		devInputRoot, sysInputRoot = oldDev, oldSys
+	})
+	devInputRoot, sysInputRoot = t.TempDir(), t.TempDir()
+	return devInputRoot, sysInputRoot
+}
+
+// writeFakeFiles creates the files, given as path relative to root and content
+func writeFakeFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// number of bits in each word of a sysfs capability bitmap
+const wordBits = int(unsafe.Sizeof(uintptr(0))) * 8
+
+func TestEnumerate(t *testing.T) {
+	dev, sys := fakeInputTree(t)
+
+	// the device nodes are plain files, so the joydev ioctls fail and sysfs is used
+	writeFakeFiles(t, dev, map[string]string{
+		"js1":    "",
+		"js0":    "",
+		"event3": "",
+		"mice":   "",
+	})
+	writeFakeFiles(t, sys, map[string]string{
+		"js0/device/name":             "Fake Pad\n",
+		"js0/device/uniq":             "00:11:22:33:44:55\n",
+		"js0/device/id/bustype":       "0003\n",
+		"js0/device/id/vendor":        "045e\n",
+		"js0/device/id/product":       "028e\n",
+		"js0/device/id/version":       "0110\n",
+		"js0/device/capabilities/abs": "3003f\n",
+		"js0/device/capabilities/key": "7fdb" + strings.Repeat(" 0", _BTN_MISC/wordBits) + "\n",
+		"js0/device/event3/dev":       "13:67\n",
+	})
+
+	devices, err := Enumerate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 2 {
+		t.Fatalf("got %d devices, want 2: %v", len(devices), devices)
+	}
+
+	want := DeviceInfo{
+		ID:          0,
+		Path:        filepath.Join(dev, "js0"),
+		EventPath:   filepath.Join(dev, "event3"),
+		Name:        "Fake Pad",
+		Serial:      "00:11:22:33:44:55",
+		BusType:     0x03,
+		Vendor:      0x045e,
+		Product:     0x028e,
+		Version:     0x0110,
+		AxisCount:   8,
+		ButtonCount: 13,
+	}
+	if !reflect.DeepEqual(devices[0], want) {
+		t.Errorf("got %+v, want %+v", devices[0], want)
+	}
+
+	// no sysfs entry at all
+	if want := (DeviceInfo{ID: 1, Path: filepath.Join(dev, "js1")}); !reflect.DeepEqual(devices[1], want) {
+		t.Errorf("got %+v, want %+v", devices[1], want)
+	}
+}
+
+func TestEnumerateMissingRoot(t *testing.T) {
+	fakeInputTree(t)
+	devInputRoot = filepath.Join(devInputRoot, "missing")
+
+	if _, err := Enumerate(); err == nil {
+		t.Error("expected an error when the device directory does not exist")
+	}
+}
+
+func TestCountSysfsBits(t *testing.T) {
+	// buttons are counted from BTN_MISC, the first bit of a word
+	buttons := strings.Repeat(" 0", _BTN_MISC/wordBits)
+
+	tests := []struct {
+		bitmap string
+		from   int
+		want   int
+	}{
+		{"", 0, 0},
+		{"0", 0, 0},
+		{"3f", 0, 6},
+		{"3f", 2, 4},
+		{"3003f", 0, 8},
+		{"1 0", 0, 1},
+		{"1 0", wordBits, 1},
+		{"1 0", wordBits + 1, 0},
+		{"ff" + buttons, _BTN_MISC, 8},
+		{"ff" + buttons, _BTN_MISC + 4, 4},
+		{"ff" + buttons, 0, 8},
+		{"not hex", 0, 0},
+	}
+	for _, test := range tests {
+		if got := countSysfsBits(test.bitmap, test.from); got != test.want {
+			t.Errorf("countSysfsBits(%q, %d) = %d, want %d", test.bitmap, test.from, got, test.want)
+		}
+	}
+}
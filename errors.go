@@ -0,0 +1,49 @@
+package joystick
+
+import (
+	"errors"
+)
+
+// Errors returned by all backends. Test for them with errors.Is
+var (
+	// ErrNotFound is returned when the requested device does not exist
+	ErrNotFound = errors.New("joystick not found")
+	// ErrPermission is returned when the device exists but may not be opened
+	ErrPermission = errors.New("permission denied")
+	// ErrNotJoystick is returned when the device exists but is not a joystick
+	ErrNotJoystick = errors.New("device is not a joystick")
+	// ErrDisconnected is returned when an open joystick can no longer be read
+	ErrDisconnected = errors.New("joystick disconnected")
+	// ErrClosed is returned when reading a joystick after Close
+	ErrClosed = errors.New("joystick closed")
+)
+
+// OpenError records a failure to open a joystick, and the operation that failed
+type OpenError struct {
+	// Path of the device, where devices have a path
+	Path string
+	// Operation that failed, for example "open" or "JSIOCGAXES"
+	Op string
+	// Underlying error, typically a syscall.Errno or one of the sentinel errors
+	Err error
+
+	// sentinel error matched by Is, when Err is not a sentinel error itself
+	kind error
+}
+
+func (e *OpenError) Error() string {
+	s := "joystick: " + e.Op
+	if e.Path != "" {
+		s += " " + e.Path
+	}
+	return s + ": " + e.Err.Error()
+}
+
+func (e *OpenError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the error matches one of the sentinel errors
+func (e *OpenError) Is(target error) bool {
+	return e.kind != nil && e.kind == target
+}
@@ -0,0 +1,13 @@
+// +build !linux
+
+package joystick
+
+import (
+	"errors"
+)
+
+// OpenEvdev opens the Joystick for reading through the Linux evdev interface.
+// It is only supported under linux
+func OpenEvdev(id int) (EvdevJoystick, error) {
+	return nil, errors.New("evdev is only supported on linux")
+}
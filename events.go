@@ -0,0 +1,154 @@
+package joystick
+
+import (
+	"sync"
+)
+
+// EventType identifies the kind of input that generated an Event
+type EventType uint8
+
+const (
+	// EventButton is generated when a button is pressed or released
+	EventButton EventType = iota + 1
+	// EventAxis is generated when an axis moves
+	EventAxis
+)
+
+// Event holds a single change to the state of a joystick
+type Event struct {
+	// Type of input that changed
+	Type EventType
+	// Index of the axis or button that changed
+	Number int
+	// New axis value, or for buttons 1 = pressed, 0 = released
+	Value int
+	// Event timestamp in milliseconds, as supplied by the driver
+	Time uint32
+	// Init is set on the synthetic events sent when the device is opened to report its initial state
+	Init bool
+}
+
+// OverflowPolicy decides what happens to an event when the channel returned by Events() is full
+type OverflowPolicy int
+
+const (
+	// DropNewest discards the incoming event, keeping the buffered ones
+	DropNewest OverflowPolicy = iota
+	// DropOldest discards the oldest buffered event to make room for the incoming one
+	DropOldest
+	// Block waits until the receiver makes room. While blocked the joystick State is not updated
+	Block
+)
+
+// EventReader is implemented by Joysticks that can deliver each individual input event,
+// in addition to the polled State returned by Read()
+type EventReader interface {
+	// Events returns a channel that receives every event read from the joystick, in order.
+	// bufferSize is the capacity of the channel and policy decides what happens when it is full.
+	// The channel is closed when the joystick is closed or can no longer be read.
+	Events(bufferSize int, policy OverflowPolicy) <-chan Event
+}
+
+type eventSub struct {
+	ch     chan Event
+	policy OverflowPolicy
+}
+
+// eventHub fans events out from a single producer to any number of subscribers
+type eventHub struct {
+	mutex       sync.Mutex
+	subs        []eventSub
+	closed      bool
+	stop        chan struct{}
+	interrupted bool
+}
+
+func (h *eventHub) subscribe(bufferSize int, policy OverflowPolicy) <-chan Event {
+	if bufferSize < 0 {
+		bufferSize = 0
+	}
+	ch := make(chan Event, bufferSize)
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.closed {
+		close(ch)
+	} else {
+		h.subs = append(h.subs, eventSub{ch, policy})
+	}
+	return ch
+}
+
+// publish delivers ev to every subscriber. Must only be called from the producer goroutine
+func (h *eventHub) publish(ev Event) {
+	h.mutex.Lock()
+	subs, stop := h.subs, h.stopChan()
+	h.mutex.Unlock()
+
+	for _, s := range subs {
+		s.send(ev, stop)
+	}
+}
+
+// interrupt releases the producer if it is blocked on a Block subscriber, and makes it
+// drop events for those subscribers from then on. Can be called from any goroutine
+func (h *eventHub) interrupt() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if !h.interrupted {
+		close(h.stopChan())
+		h.interrupted = true
+	}
+}
+
+// stopChan returns the channel closed by interrupt. Must be called with the mutex held
+func (h *eventHub) stopChan() chan struct{} {
+	if h.stop == nil {
+		h.stop = make(chan struct{})
+	}
+	return h.stop
+}
+
+// close closes every subscriber channel. Must only be called from the producer goroutine
+func (h *eventHub) close() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.closed {
+		return
+	}
+	for _, s := range h.subs {
+		close(s.ch)
+	}
+	h.subs = nil
+	h.closed = true
+}
+
+func (s eventSub) send(ev Event, stop <-chan struct{}) {
+	switch {
+	case s.policy == Block:
+		select {
+		case s.ch <- ev:
+		case <-stop:
+		}
+	case s.policy == DropOldest && cap(s.ch) > 0:
+		for {
+			select {
+			case s.ch <- ev:
+				return
+			default:
+			}
+			select {
+			case <-s.ch:
+			default:
+			}
+		}
+	default:
+		select {
+		case s.ch <- ev:
+		default:
+		}
+	}
+}
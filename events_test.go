@@ -0,0 +1,103 @@
+package joystick
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// drain returns the values of the events buffered in ch
+func drain(ch <-chan Event) []int {
+	var values []int
+	for {
+		select {
+		case ev, ok := <-ch:
+			if !ok {
+				return values
+			}
+			values = append(values, ev.Value)
+		default:
+			return values
+		}
+	}
+}
+
+func TestOverflowPolicy(t *testing.T) {
+	tests := []struct {
+		policy     OverflowPolicy
+		bufferSize int
+		want       []int
+	}{
+		{DropNewest, 2, []int{1, 2}},
+		{DropOldest, 2, []int{4, 5}},
+		// a subscriber without a buffer only gets the events it is waiting for
+		{DropNewest, 0, nil},
+		{DropOldest, 0, nil},
+	}
+	for _, test := range tests {
+		var h eventHub
+		ch := h.subscribe(test.bufferSize, test.policy)
+		for value := 1; value <= 5; value++ {
+			h.publish(Event{Type: EventAxis, Value: value})
+		}
+		if got := drain(ch); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("policy %d, buffer %d: kept %v, want %v", test.policy, test.bufferSize, got, test.want)
+		}
+	}
+}
+
+func TestEventHubClose(t *testing.T) {
+	var h eventHub
+	a := h.subscribe(4, DropNewest)
+	h.publish(Event{Value: 1})
+	h.close()
+
+	// buffered events are still delivered, then the channel is closed
+	if got := drain(a); len(got) != 1 || got[0] != 1 {
+		t.Errorf("got %v after close, want [1]", got)
+	}
+	if _, ok := <-a; ok {
+		t.Error("channel not closed")
+	}
+	// subscribing after close returns a closed channel
+	if _, ok := <-h.subscribe(4, Block); ok {
+		t.Error("subscription after close not closed")
+	}
+}
+
+func TestCloseReleasesBlockedProducer(t *testing.T) {
+	vj := NewVirtualJoystick("pad", 1, 0)
+	events := vj.Events(1, Block)
+
+	// the first change fills the buffer, the second blocks the producer
+	produced := make(chan struct{})
+	go func() {
+		for value := 1; value <= 3; value++ {
+			vj.SetAxis(0, value)
+		}
+		close(produced)
+	}()
+	select {
+	case <-produced:
+		t.Fatal("producer not blocked by a full Block subscriber")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		vj.Close()
+		close(closed)
+	}()
+	for _, ch := range []chan struct{}{produced, closed} {
+		select {
+		case <-ch:
+		case <-time.After(time.Second):
+			t.Fatal("Close did not release the blocked producer")
+		}
+	}
+
+	// the event buffered before Close is kept, then the channel is closed
+	if got := drain(events); len(got) != 1 || got[0] != 1 {
+		t.Errorf("got %v, want [1]", got)
+	}
+}
@@ -0,0 +1,89 @@
+package joystick
+
+import (
+	"context"
+)
+
+// Filter transforms the State read from a joystick
+type Filter interface {
+	// Apply modifies s in place
+	Apply(s *State)
+}
+
+// FilterFunc adapts an ordinary function to a Filter
+type FilterFunc func(s *State)
+
+func (f FilterFunc) Apply(s *State) {
+	f(s)
+}
+
+type filteredJoystick struct {
+	js      Joystick
+	filters []Filter
+}
+
+// filteredCodeMapper is a filteredJoystick that forwards the axis and button codes of its joystick
+type filteredCodeMapper struct {
+	*filteredJoystick
+	CodeMapper
+}
+
+// Filtered returns a Joystick that reads js and applies the filters, in order,
+// to each State before returning it.
+//
+// The Joystick implements StateReader and ChangeWaiter, and CodeMapper if js does, so that
+// FindAxis and gamepad mappings work on it. Other optional interfaces of js are not forwarded:
+// use js itself for events, which are not filtered, calibration, force feedback and WaitReady
+func Filtered(js Joystick, filters ...Filter) Joystick {
+	f := &filteredJoystick{js, filters}
+	if cm, ok := js.(CodeMapper); ok {
+		return &filteredCodeMapper{f, cm}
+	}
+	return f
+}
+
+func (f *filteredJoystick) AxisCount() int {
+	return f.js.AxisCount()
+}
+
+func (f *filteredJoystick) ButtonCount() int {
+	return f.js.ButtonCount()
+}
+
+func (f *filteredJoystick) Name() string {
+	return f.js.Name()
+}
+
+func (f *filteredJoystick) Read() (State, error) {
+	var state State
+	err := f.ReadInto(&state)
+	return state, err
+}
+
+// ReadInto reads and filters the state. The state returned with an error, such as the
+// last state of a disconnected joystick, is filtered too
+func (f *filteredJoystick) ReadInto(state *State) error {
+	err := ReadInto(f.js, state)
+	f.apply(state)
+	return err
+}
+
+// WaitForChange waits for a change of the underlying joystick, and filters its state
+func (f *filteredJoystick) WaitForChange(ctx context.Context, since uint64) (State, error) {
+	state, err := WaitForChange(ctx, f.js, since)
+	// there is no state to filter when ctx is done first
+	if err == nil || err != ctx.Err() {
+		f.apply(&state)
+	}
+	return state, err
+}
+
+func (f *filteredJoystick) apply(state *State) {
+	for _, filter := range f.filters {
+		filter.Apply(state)
+	}
+}
+
+func (f *filteredJoystick) Close() error {
+	return f.js.Close()
+}
@@ -0,0 +1,116 @@
+package joystick
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// codedVirtual is a VirtualJoystick that reports axis and button codes
+type codedVirtual struct {
+	*VirtualJoystick
+}
+
+func (codedVirtual) AxisCodes() []AxisCode {
+	return []AxisCode{AxisX, AxisY}
+}
+
+func (codedVirtual) ButtonCodes() []ButtonCode {
+	return []ButtonCode{ButtonSouth}
+}
+
+func TestFiltered(t *testing.T) {
+	vj := NewVirtualJoystick("pad", 2, 1)
+	double := FilterFunc(func(s *State) {
+		s.AxisData[0] *= 2
+	})
+	js := Filtered(vj, AxisDeadZone(0, DeadZone{Inner: 1000}), double)
+
+	if js.Name() != "pad" || js.AxisCount() != 2 || js.ButtonCount() != 1 {
+		t.Errorf("got name %q, %d axis, %d buttons", js.Name(), js.AxisCount(), js.ButtonCount())
+	}
+
+	// filters are applied in order
+	vj.SetAxis(0, 900)
+	vj.SetAxis(1, 900)
+	state, err := js.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{0, 900}; !reflect.DeepEqual(state.AxisData, want) {
+		t.Errorf("got %v, want %v", state.AxisData, want)
+	}
+
+	vj.SetAxis(0, 1000)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	state, err = WaitForChange(ctx, js, state.Sequence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{2000, 900}; !reflect.DeepEqual(state.AxisData, want) {
+		t.Errorf("got %v after waiting, want %v", state.AxisData, want)
+	}
+
+	if _, ok := js.(CodeMapper); ok {
+		t.Error("Filtered implements CodeMapper for a joystick that does not")
+	}
+	if FindAxis(js, AxisX) != -1 {
+		t.Error("FindAxis found an axis on a joystick without codes")
+	}
+
+	if err := js.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := vj.Read(); err != ErrClosed {
+		t.Errorf("got %v reading the filtered joystick after Close, want ErrClosed", err)
+	}
+}
+
+func TestFilteredCodeMapper(t *testing.T) {
+	js := Filtered(codedVirtual{NewVirtualJoystick("pad", 2, 1)})
+
+	if FindAxis(js, AxisY) != 1 || FindButton(js, ButtonSouth) != 0 {
+		t.Error("codes of the joystick not forwarded by Filtered")
+	}
+	if _, ok := DefaultGamepadMapping(js); !ok {
+		t.Error("no default gamepad mapping for a filtered gamepad")
+	}
+}
+
+func TestFilteredError(t *testing.T) {
+	vj := NewVirtualJoystick("pad", 2, 1)
+	double := FilterFunc(func(s *State) {
+		s.AxisData[1] *= 2
+	})
+	js := Filtered(vj, AxisDeadZone(0, DeadZone{Inner: 1000}), double)
+
+	// a wait that times out returns no state, and does not filter it
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if _, err := WaitForChange(ctx, js, 0); err != context.DeadlineExceeded {
+		t.Fatalf("got %v, want DeadlineExceeded", err)
+	}
+
+	// the last state of a disconnected joystick is filtered like any other
+	vj.SetAxis(0, 500)
+	vj.SetAxis(1, 500)
+	vj.Disconnect()
+	state, err := js.Read()
+	if !errors.Is(err, ErrDisconnected) {
+		t.Fatalf("got %v, want ErrDisconnected", err)
+	}
+	if want := []int{0, 1000}; !reflect.DeepEqual(state.AxisData, want) {
+		t.Errorf("Read returned %v with the error, want %v", state.AxisData, want)
+	}
+
+	state, err = WaitForChange(context.Background(), js, 0)
+	if !errors.Is(err, ErrDisconnected) {
+		t.Fatalf("got %v, want ErrDisconnected", err)
+	}
+	if want := []int{0, 1000}; !reflect.DeepEqual(state.AxisData, want) {
+		t.Errorf("WaitForChange returned %v with the error, want %v", state.AxisData, want)
+	}
+}
@@ -0,0 +1,126 @@
+package joystick
+
+import (
+	"errors"
+	"time"
+)
+
+// EffectType identifies a kind of force feedback effect. The values are the linux FF_ codes
+type EffectType uint16
+
+const (
+	EffectRumble   EffectType = 0x50
+	EffectPeriodic EffectType = 0x51
+	EffectConstant EffectType = 0x52
+	EffectSpring   EffectType = 0x53
+	EffectFriction EffectType = 0x54
+	EffectDamper   EffectType = 0x55
+	EffectInertia  EffectType = 0x56
+	EffectRamp     EffectType = 0x57
+)
+
+// Waveform selects the shape of a periodic effect. The values are the linux FF_ codes
+type Waveform uint16
+
+const (
+	WaveSquare   Waveform = 0x58
+	WaveTriangle Waveform = 0x59
+	WaveSine     Waveform = 0x5a
+	WaveSawUp    Waveform = 0x5b
+	WaveSawDown  Waveform = 0x5c
+)
+
+// Envelope shapes the start and end of constant, periodic and ramp effects
+type Envelope struct {
+	AttackLength time.Duration
+	AttackLevel  uint16
+	FadeLength   time.Duration
+	FadeLevel    uint16
+}
+
+// Condition describes how a spring, friction, damper or inertia effect acts on one axis
+type Condition struct {
+	// Maximum force when the axis is right or left of center
+	RightSaturation uint16
+	LeftSaturation  uint16
+	// How quickly the force increases when the axis is right or left of center
+	RightCoeff int16
+	LeftCoeff  int16
+	// Size of the dead zone around Center
+	Deadband uint16
+	// Position of the center of the effect on the axis
+	Center int16
+}
+
+// Effect describes a force feedback effect. Only the fields used by Type need to be set
+type Effect struct {
+	Type EffectType
+	// Direction of the force: 0 = down, 0x4000 = left, 0x8000 = up, 0xc000 = right
+	Direction uint16
+	// How long the effect plays for, 0 = forever. Durations have millisecond precision
+	Length time.Duration
+	// Delay before the effect starts playing
+	Delay time.Duration
+
+	// EffectRumble: magnitude of the heavy and light motors
+	StrongMagnitude uint16
+	WeakMagnitude   uint16
+
+	// EffectConstant: strength of the force
+	Level int16
+
+	// EffectRamp: strength of the force at the start and at the end
+	StartLevel int16
+	EndLevel   int16
+
+	// EffectPeriodic: shape, period and strength of the force
+	Waveform  Waveform
+	Period    time.Duration
+	Magnitude int16
+	Offset    int16
+	Phase     uint16
+
+	// EffectConstant, EffectRamp and EffectPeriodic: start and end of the effect
+	Envelope Envelope
+
+	// EffectSpring, EffectFriction, EffectDamper and EffectInertia: how the effect acts
+	// on the X and Y axis
+	Conditions [2]Condition
+}
+
+// Interface ForceFeedback controls the force feedback effects of a joystick.
+// Obtain one with OpenForceFeedback()
+type ForceFeedback interface {
+	// Supported returns the types of effect the device can play
+	Supported() []EffectType
+	// MaxEffects returns how many effects can be uploaded at once
+	MaxEffects() int
+	// Upload sends an effect to the device and returns its id
+	Upload(effect Effect) (int, error)
+	// Update replaces the uploaded effect with the supplied id
+	Update(id int, effect Effect) error
+	// Erase removes an uploaded effect from the device
+	Erase(id int) error
+	// Play starts an uploaded effect, repeating it count times
+	Play(id int, count int) error
+	// Stop stops an effect that is playing
+	Stop(id int) error
+	// SetGain sets the overall strength of all effects, from 0 to 0xffff
+	SetGain(gain uint16) error
+	// Close releases the force feedback device. Uploaded effects are erased
+	Close() error
+}
+
+// ForceFeedbacker is implemented by Joysticks that can give access to force feedback
+type ForceFeedbacker interface {
+	ForceFeedback() (ForceFeedback, error)
+}
+
+// OpenForceFeedback returns the force feedback controls of an opened joystick.
+// Under linux this opens the evdev device node of the joystick for writing
+func OpenForceFeedback(js Joystick) (ForceFeedback, error) {
+	if ffj, ok := js.(ForceFeedbacker); ok {
+		return ffj.ForceFeedback()
+	}
+	return nil, errors.New("force feedback is not supported by this joystick")
+}
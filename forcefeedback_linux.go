@@ -0,0 +1,276 @@
+// +build linux
+
+package joystick
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	_EV_FF   uint16 = 0x15
+	_FF_GAIN uint16 = 0x60
+	_FF_MAX         = 0x7f
+
+	// size of the union in struct ff_effect: the size of struct ff_periodic_effect
+	_FF_UNION_SIZE = 24 + unsafe.Sizeof(uintptr(0))
+)
+
+var (
+	_EVIOCSFF      = _IOW('E', 0x80, int(unsafe.Sizeof(ffEffect{}))) /* send a force effect to a force feedback device */
+	_EVIOCRMFF     = _IOW('E', 0x81, int(unsafe.Sizeof(int32(0))))   /* erase a force effect */
+	_EVIOCGEFFECTS = _IOR('E', 0x84, int(unsafe.Sizeof(int32(0))))   /* report number of effects playable at the same time */
+)
+
+// ffEffect mirrors struct ff_effect
+type ffEffect struct {
+	Type      uint16
+	ID        int16
+	Direction uint16
+	Trigger   struct{ Button, Interval uint16 }
+	Replay    struct{ Length, Delay uint16 }
+	U         [_FF_UNION_SIZE / unsafe.Sizeof(uintptr(0))]uintptr
+}
+
+type ffEnvelope struct {
+	AttackLength uint16
+	AttackLevel  uint16
+	FadeLength   uint16
+	FadeLevel    uint16
+}
+
+type ffConstant struct {
+	Level    int16
+	Envelope ffEnvelope
+}
+
+type ffRamp struct {
+	StartLevel int16
+	EndLevel   int16
+	Envelope   ffEnvelope
+}
+
+type ffPeriodic struct {
+	Waveform   uint16
+	Period     uint16
+	Magnitude  int16
+	Offset     int16
+	Phase      uint16
+	Envelope   ffEnvelope
+	CustomLen  uint32
+	CustomData uintptr
+}
+
+type ffCondition struct {
+	RightSaturation uint16
+	LeftSaturation  uint16
+	RightCoeff      int16
+	LeftCoeff       int16
+	Deadband        uint16
+	Center          int16
+}
+
+type ffRumble struct {
+	StrongMagnitude uint16
+	WeakMagnitude   uint16
+}
+
+// ffDevice is the device node force feedback commands are sent to. Tests replace it with a fake
+type ffDevice interface {
+	io.WriteCloser
+	ioctl(req int, ptr unsafe.Pointer) syscall.Errno
+	ioctlInt(req int, val int) syscall.Errno
+}
+
+type forceFeedback struct {
+	dev        ffDevice
+	supported  []EffectType
+	maxEffects int
+}
+
+// ForceFeedback opens the evdev device node of the joystick for writing
+func (js *joystickImpl) ForceFeedback() (ForceFeedback, error) {
+	if js.file == nil {
+		return nil, &OpenError{Op: "open", Err: errors.New("no device node")}
+	}
+	path := js.file.Name()
+	if _, ok := js.source.(*evdevSource); !ok {
+		// find the evdev node belonging to the same input device as the joydev node.
+		// Links such as /dev/input/by-id/... are resolved to get the jsN name of the node
+		node := path
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			node = resolved
+		}
+		var info DeviceInfo
+		readSysfsInfo(&info, filepath.Join(sysInputRoot, filepath.Base(node), "device"))
+		if info.EventPath == "" {
+			return nil, &OpenError{Path: path, Op: "open", Err: errors.New("no evdev device found")}
+		}
+		path = info.EventPath
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR, 0666)
+	if err != nil {
+		return nil, newOpenError(path, "open", err)
+	}
+
+	ff, err := newForceFeedback(deviceFile{f})
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return ff, nil
+}
+
+func newForceFeedback(dev ffDevice) (*forceFeedback, error) {
+	var ffBits [(_FF_MAX + 8) / 8]byte
+	var maxEffects int32
+
+	if ioerr := dev.ioctl(_EVIOCGBIT(int(_EV_FF), len(ffBits)), unsafe.Pointer(&ffBits)); ioerr != 0 {
+		return nil, &OpenError{Op: "EVIOCGBIT", Err: ioerr}
+	}
+
+	if ioerr := dev.ioctl(_EVIOCGEFFECTS, unsafe.Pointer(&maxEffects)); ioerr != 0 {
+		return nil, &OpenError{Op: "EVIOCGEFFECTS", Err: ioerr}
+	}
+
+	ff := &forceFeedback{dev: dev, maxEffects: int(maxEffects)}
+	for t := EffectRumble; t <= EffectRamp; t++ {
+		if testBit(ffBits[:], int(t)) {
+			ff.supported = append(ff.supported, t)
+		}
+	}
+
+	if len(ff.supported) == 0 {
+		return nil, &OpenError{Op: "EVIOCGBIT", Err: errors.New("device does not support force feedback")}
+	}
+	return ff, nil
+}
+
+func (ff *forceFeedback) Supported() []EffectType {
+	return append([]EffectType(nil), ff.supported...)
+}
+
+func (ff *forceFeedback) MaxEffects() int {
+	return ff.maxEffects
+}
+
+func (ff *forceFeedback) Upload(effect Effect) (int, error) {
+	return ff.upload(-1, effect)
+}
+
+func (ff *forceFeedback) Update(id int, effect Effect) error {
+	_, err := ff.upload(id, effect)
+	return err
+}
+
+func (ff *forceFeedback) upload(id int, effect Effect) (int, error) {
+	e, err := encodeEffect(int16(id), effect)
+	if err != nil {
+		return -1, err
+	}
+	if ioerr := ff.dev.ioctl(_EVIOCSFF, unsafe.Pointer(&e)); ioerr != 0 {
+		return -1, fmt.Errorf("EVIOCSFF: %w", ioerr)
+	}
+	// the driver reports the id allocated to new effects
+	return int(e.ID), nil
+}
+
+func (ff *forceFeedback) Erase(id int) error {
+	if ioerr := ff.dev.ioctlInt(_EVIOCRMFF, id); ioerr != 0 {
+		return fmt.Errorf("EVIOCRMFF: %w", ioerr)
+	}
+	return nil
+}
+
+func (ff *forceFeedback) Play(id int, count int) error {
+	return ff.write(uint16(id), int32(count))
+}
+
+func (ff *forceFeedback) Stop(id int) error {
+	return ff.write(uint16(id), 0)
+}
+
+func (ff *forceFeedback) SetGain(gain uint16) error {
+	return ff.write(_FF_GAIN, int32(gain))
+}
+
+func (ff *forceFeedback) Close() error {
+	return ff.dev.Close()
+}
+
+// write sends an EV_FF input_event to the device
+func (ff *forceFeedback) write(code uint16, value int32) error {
+	_, err := ff.dev.Write(encodeFFEvent(code, value))
+	return err
+}
+
+func encodeFFEvent(code uint16, value int32) []byte {
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, inputEvent{Type: _EV_FF, Code: code, Value: value})
+	return b.Bytes()
+}
+
+// encodeEffect converts an Effect to a struct ff_effect
+func encodeEffect(id int16, effect Effect) (ffEffect, error) {
+	var e ffEffect
+	e.Type = uint16(effect.Type)
+	e.ID = id
+	e.Direction = effect.Direction
+	e.Replay.Length = durationMs(effect.Length)
+	e.Replay.Delay = durationMs(effect.Delay)
+
+	u := unsafe.Pointer(&e.U)
+	env := ffEnvelope{
+		AttackLength: durationMs(effect.Envelope.AttackLength),
+		AttackLevel:  effect.Envelope.AttackLevel,
+		FadeLength:   durationMs(effect.Envelope.FadeLength),
+		FadeLevel:    effect.Envelope.FadeLevel,
+	}
+
+	switch effect.Type {
+	case EffectRumble:
+		*(*ffRumble)(u) = ffRumble{effect.StrongMagnitude, effect.WeakMagnitude}
+	case EffectConstant:
+		*(*ffConstant)(u) = ffConstant{effect.Level, env}
+	case EffectRamp:
+		*(*ffRamp)(u) = ffRamp{effect.StartLevel, effect.EndLevel, env}
+	case EffectPeriodic:
+		*(*ffPeriodic)(u) = ffPeriodic{
+			Waveform:  uint16(effect.Waveform),
+			Period:    durationMs(effect.Period),
+			Magnitude: effect.Magnitude,
+			Offset:    effect.Offset,
+			Phase:     effect.Phase,
+			Envelope:  env,
+		}
+	case EffectSpring, EffectFriction, EffectDamper, EffectInertia:
+		*(*[2]ffCondition)(u) = [2]ffCondition{
+			ffCondition(effect.Conditions[0]),
+			ffCondition(effect.Conditions[1]),
+		}
+	default:
+		return e, fmt.Errorf("unsupported effect type 0x%02x", uint16(effect.Type))
+	}
+	return e, nil
+}
+
+// durationMs converts d to the millisecond durations used by ff_effect
+func durationMs(d time.Duration) uint16 {
+	ms := d / time.Millisecond
+	if ms < 0 {
+		return 0
+	}
+	if ms > 0xffff {
+		return 0xffff
+	}
+	return uint16(ms)
+}
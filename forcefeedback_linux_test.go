@@ -0,0 +1,247 @@
+// +build linux
+
+package joystick
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// fakeFFDevice records the effects uploaded and the events written to it
+type fakeFFDevice struct {
+	bytes.Buffer
+	ffBits   [(_FF_MAX + 8) / 8]byte
+	uploaded []ffEffect
+	erased   []int
+	closed   bool
+}
+
+func (d *fakeFFDevice) Close() error {
+	d.closed = true
+	return nil
+}
+
+func (d *fakeFFDevice) ioctl(req int, ptr unsafe.Pointer) syscall.Errno {
+	switch req {
+	case _EVIOCGBIT(int(_EV_FF), len(d.ffBits)):
+		*(*[len(d.ffBits)]byte)(ptr) = d.ffBits
+	case _EVIOCGEFFECTS:
+		*(*int32)(ptr) = 16
+	case _EVIOCSFF:
+		e := (*ffEffect)(ptr)
+		if e.ID < 0 {
+			e.ID = int16(len(d.uploaded))
+		}
+		d.uploaded = append(d.uploaded, *e)
+	default:
+		return syscall.ENOTTY
+	}
+	return 0
+}
+
+func (d *fakeFFDevice) ioctlInt(req int, val int) syscall.Errno {
+	if req != _EVIOCRMFF {
+		return syscall.ENOTTY
+	}
+	d.erased = append(d.erased, val)
+	return 0
+}
+
+// effectBytes returns the memory of e, as passed to the driver
+func effectBytes(e *ffEffect) []byte {
+	return (*[unsafe.Sizeof(ffEffect{})]byte)(unsafe.Pointer(e))[:]
+}
+
+func u16(b []byte, offset int) uint16 {
+	return *(*uint16)(unsafe.Pointer(&b[offset]))
+}
+
+func TestFFEffectLayout(t *testing.T) {
+	var e ffEffect
+	if offset := unsafe.Offsetof(e.U); offset != 16 {
+		t.Errorf("union at offset %d, want 16", offset)
+	}
+	if runtime.GOARCH == "amd64" && unsafe.Sizeof(e) != 48 {
+		t.Errorf("ff_effect is %d bytes, want 48", unsafe.Sizeof(e))
+	}
+	if size := unsafe.Sizeof(ffPeriodic{}); size != _FF_UNION_SIZE {
+		t.Errorf("ff_periodic_effect is %d bytes, want %d", size, _FF_UNION_SIZE)
+	}
+	if size := unsafe.Sizeof([2]ffCondition{}); size != 24 {
+		t.Errorf("ff_condition_effect[2] is %d bytes, want 24", size)
+	}
+}
+
+func TestEncodeEffect(t *testing.T) {
+	tests := []struct {
+		effect Effect
+		// uint16 values expected at offsets of the ff_effect
+		want map[int]uint16
+	}{
+		{
+			Effect{Type: EffectRumble, Length: 500 * time.Millisecond, Delay: time.Second, StrongMagnitude: 0x8000, WeakMagnitude: 0x1234},
+			map[int]uint16{0: 0x50, 2: 7, 10: 500, 12: 1000, 16: 0x8000, 18: 0x1234},
+		},
+		{
+			Effect{Type: EffectConstant, Direction: 0x4000, Level: -2, Envelope: Envelope{AttackLength: 10 * time.Millisecond, AttackLevel: 3, FadeLength: 20 * time.Millisecond, FadeLevel: 4}},
+			map[int]uint16{0: 0x52, 4: 0x4000, 16: 0xfffe, 18: 10, 20: 3, 22: 20, 24: 4},
+		},
+		{
+			Effect{Type: EffectRamp, StartLevel: 100, EndLevel: -100, Envelope: Envelope{FadeLevel: 9}},
+			map[int]uint16{0: 0x57, 16: 100, 18: 0xff9c, 24: 0, 26: 9},
+		},
+		{
+			Effect{Type: EffectPeriodic, Waveform: WaveSine, Period: 50 * time.Millisecond, Magnitude: 0x4000, Offset: -1, Phase: 90, Envelope: Envelope{AttackLevel: 5}},
+			map[int]uint16{0: 0x51, 16: 0x5a, 18: 50, 20: 0x4000, 22: 0xffff, 24: 90, 28: 5},
+		},
+		{
+			Effect{Type: EffectSpring, Conditions: [2]Condition{
+				{RightSaturation: 1, LeftSaturation: 2, RightCoeff: 3, LeftCoeff: -4, Deadband: 5, Center: -6},
+				{RightSaturation: 7, LeftSaturation: 8, RightCoeff: 9, LeftCoeff: 10, Deadband: 11, Center: 12},
+			}},
+			map[int]uint16{0: 0x53, 16: 1, 18: 2, 20: 3, 22: 0xfffc, 24: 5, 26: 0xfffa, 28: 7, 30: 8, 32: 9, 34: 10, 36: 11, 38: 12},
+		},
+		{
+			Effect{Type: EffectInertia, Conditions: [2]Condition{{Center: 1}, {Center: 2}}},
+			map[int]uint16{0: 0x56, 26: 1, 38: 2},
+		},
+	}
+
+	for _, test := range tests {
+		e, err := encodeEffect(7, test.effect)
+		if err != nil {
+			t.Errorf("type 0x%02x: %v", uint16(test.effect.Type), err)
+			continue
+		}
+		b := effectBytes(&e)
+		for offset, want := range test.want {
+			if got := u16(b, offset); got != want {
+				t.Errorf("type 0x%02x: got 0x%04x at offset %d, want 0x%04x", uint16(test.effect.Type), got, offset, want)
+			}
+		}
+	}
+
+	if _, err := encodeEffect(0, Effect{Type: 0x42}); err == nil {
+		t.Error("expected an error for an unknown effect type")
+	}
+}
+
+func TestForceFeedback(t *testing.T) {
+	dev := &fakeFFDevice{}
+	for _, effect := range []EffectType{EffectRumble, EffectSpring, EffectDamper} {
+		dev.ffBits[effect/8] |= 1 << (effect % 8)
+	}
+
+	ff, err := newForceFeedback(dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []EffectType{EffectRumble, EffectSpring, EffectDamper}; !reflect.DeepEqual(ff.Supported(), want) {
+		t.Errorf("got supported effects %v, want %v", ff.Supported(), want)
+	}
+	if ff.MaxEffects() != 16 {
+		t.Errorf("got %d effects, want 16", ff.MaxEffects())
+	}
+
+	// every advertised effect can be uploaded
+	for i, effect := range ff.Supported() {
+		id, err := ff.Upload(Effect{Type: effect})
+		if err != nil {
+			t.Fatalf("uploading 0x%02x: %v", uint16(effect), err)
+		}
+		if id != i {
+			t.Errorf("got id %d, want %d", id, i)
+		}
+	}
+	if err := ff.Update(1, Effect{Type: EffectSpring, Conditions: [2]Condition{{Deadband: 100}}}); err != nil {
+		t.Fatal(err)
+	}
+	if last := dev.uploaded[len(dev.uploaded)-1]; last.ID != 1 || u16(effectBytes(&last), 24) != 100 {
+		t.Errorf("update sent id %d with deadband %d", last.ID, u16(effectBytes(&last), 24))
+	}
+
+	if err := ff.Play(2, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := ff.Stop(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := ff.SetGain(0xc000); err != nil {
+		t.Fatal(err)
+	}
+	if err := ff.Erase(2); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dev.erased, []int{2}) {
+		t.Errorf("erased %v, want [2]", dev.erased)
+	}
+
+	// each command is written as an EV_FF input_event
+	want := []inputEvent{
+		{Type: _EV_FF, Code: 2, Value: 3},
+		{Type: _EV_FF, Code: 2, Value: 0},
+		{Type: _EV_FF, Code: _FF_GAIN, Value: 0xc000},
+	}
+	for i, w := range want {
+		var ie inputEvent
+		if err := binary.Read(&dev.Buffer, binary.LittleEndian, &ie); err != nil {
+			t.Fatalf("event %d: %v", i, err)
+		}
+		if ie != w {
+			t.Errorf("event %d: got %+v, want %+v", i, ie, w)
+		}
+	}
+	if dev.Len() != 0 {
+		t.Errorf("%d unexpected bytes written", dev.Len())
+	}
+
+	if err := ff.Close(); err != nil || !dev.closed {
+		t.Errorf("Close returned %v, device closed: %v", err, dev.closed)
+	}
+}
+
+func TestForceFeedbackUnsupported(t *testing.T) {
+	if _, err := newForceFeedback(&fakeFFDevice{}); err == nil {
+		t.Error("expected an error for a device without force feedback")
+	}
+}
+
+func TestForceFeedbackEventNode(t *testing.T) {
+	dev, sys := fakeInputTree(t)
+	writeFakeFiles(t, dev, map[string]string{"js0": ""})
+	writeFakeFiles(t, sys, map[string]string{"js0/device/event3/dev": "13:67\n"})
+	link := filepath.Join(dev, "by-id", "usb-Fake_Pad-joystick")
+	if err := os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../js0", link); err != nil {
+		t.Fatal(err)
+	}
+
+	// the joystick opened through the link, and the node opened through its jsN name
+	for _, path := range []string{link, filepath.Join(dev, "js0")} {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		js := newJoydevJoystick(f, joydevInfo{})
+		js.file = f
+
+		// the evdev node found in sysfs does not exist in the fake tree
+		_, err = js.ForceFeedback()
+		var oe *OpenError
+		if !errors.As(err, &oe) || oe.Path != filepath.Join(dev, "event3") || !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s: got %v, want the evdev node %s not found", path, err, filepath.Join(dev, "event3"))
+		}
+		f.Close()
+	}
+}
@@ -0,0 +1,366 @@
+package joystick
+
+// GamepadButton identifies a button of the standard gamepad layout
+type GamepadButton int
+
+const (
+	GamepadSouth GamepadButton = iota
+	GamepadEast
+	GamepadWest
+	GamepadNorth
+	GamepadLeftShoulder
+	GamepadRightShoulder
+	GamepadBack
+	GamepadStart
+	GamepadGuide
+	GamepadLeftStick
+	GamepadRightStick
+	GamepadDPadUp
+	GamepadDPadDown
+	GamepadDPadLeft
+	GamepadDPadRight
+
+	// GamepadButtonCount is the number of buttons in the standard gamepad layout
+	GamepadButtonCount
+)
+
+// GamepadAxis identifies an axis of the standard gamepad layout
+type GamepadAxis int
+
+const (
+	GamepadLeftX GamepadAxis = iota
+	GamepadLeftY
+	GamepadRightX
+	GamepadRightY
+	GamepadLeftTrigger
+	GamepadRightTrigger
+
+	// GamepadAxisCount is the number of axis in the standard gamepad layout
+	GamepadAxisCount
+)
+
+// BindingType selects the kind of joystick input a gamepad control is bound to
+type BindingType int
+
+const (
+	// BindNone leaves the control unbound: released, or centered
+	BindNone BindingType = iota
+	// BindButton binds the control to a joystick button
+	BindButton
+	// BindAxis binds the control to a joystick axis, or half of one
+	BindAxis
+	// BindHat binds the control to directions of a hat, reported as a pair of axis
+	BindHat
+)
+
+// AxisRange selects the part of an axis used by a binding
+type AxisRange int
+
+const (
+	// FullAxis uses the whole axis
+	FullAxis AxisRange = iota
+	// PositiveHalf uses the axis from center to its maximum
+	PositiveHalf
+	// NegativeHalf uses the axis from center to its minimum
+	NegativeHalf
+)
+
+// Hat directions used in Binding.HatMask
+const (
+	HatUp    = 1
+	HatRight = 2
+	HatDown  = 4
+	HatLeft  = 8
+)
+
+// Binding connects a control of the standard gamepad layout to an input of a joystick
+type Binding struct {
+	Type BindingType
+	// Index of the button or axis. For BindHat, index of the hat's horizontal axis;
+	// the vertical axis follows it
+	Index int
+	// For BindAxis, the part of the axis that is used
+	Range AxisRange
+	// For BindAxis, reverses the direction of the axis
+	Invert bool
+	// For BindHat, the directions that activate the control
+	HatMask int
+}
+
+// GamepadMapping describes how the inputs of a particular joystick model form a standard gamepad
+type GamepadMapping struct {
+	Name    string
+	Buttons [GamepadButtonCount]Binding
+	Axes    [GamepadAxisCount]Binding
+}
+
+// GamepadState holds the state of a joystick, arranged in the standard gamepad layout
+type GamepadState struct {
+	// The state of each button. true = pressed
+	Buttons [GamepadButtonCount]bool
+	// Stick values in the range -32767 to 32767, trigger values in the range 0 to 32767
+	Axes [GamepadAxisCount]int
+}
+
+// Pressed returns true if the specified button is pressed. Returns false for buttons
+// outside the standard gamepad layout
+func (s GamepadState) Pressed(button GamepadButton) bool {
+	if button < 0 || button >= GamepadButtonCount {
+		return false
+	}
+	return s.Buttons[button]
+}
+
+// LeftStick returns the position of the left stick
+func (s GamepadState) LeftStick() (x, y int) {
+	return s.Axes[GamepadLeftX], s.Axes[GamepadLeftY]
+}
+
+// RightStick returns the position of the right stick
+func (s GamepadState) RightStick() (x, y int) {
+	return s.Axes[GamepadRightX], s.Axes[GamepadRightY]
+}
+
+// LeftTrigger returns how far the left trigger is pulled
+func (s GamepadState) LeftTrigger() int {
+	return s.Axes[GamepadLeftTrigger]
+}
+
+// RightTrigger returns how far the right trigger is pulled
+func (s GamepadState) RightTrigger() int {
+	return s.Axes[GamepadRightTrigger]
+}
+
+// DPad returns the direction of the d-pad: -1, 0 or 1 on each axis, with y = -1 being up
+func (s GamepadState) DPad() (x, y int) {
+	if s.Buttons[GamepadDPadLeft] {
+		x--
+	}
+	if s.Buttons[GamepadDPadRight] {
+		x++
+	}
+	if s.Buttons[GamepadDPadUp] {
+		y--
+	}
+	if s.Buttons[GamepadDPadDown] {
+		y++
+	}
+	return x, y
+}
+
+// Map converts the state of a joystick to the standard gamepad layout
+func (m *GamepadMapping) Map(s State) GamepadState {
+	var g GamepadState
+
+	for i, b := range m.Buttons {
+		g.Buttons[i] = b.pressed(s)
+	}
+
+	for i, b := range m.Axes {
+		trigger := GamepadAxis(i) == GamepadLeftTrigger || GamepadAxis(i) == GamepadRightTrigger
+		g.Axes[i] = b.axis(s, trigger)
+	}
+	return g
+}
+
+// pressed returns the value of the binding as a button
+func (b Binding) pressed(s State) bool {
+	switch b.Type {
+	case BindButton:
+		return s.Pressed(b.Index)
+	case BindAxis:
+		return b.halfAxis(s) > 16384
+	case BindHat:
+		return hatDirections(s, b.Index)&b.HatMask != 0
+	}
+	return false
+}
+
+// axis returns the value of the binding as a stick axis, or a trigger
+func (b Binding) axis(s State, trigger bool) int {
+	switch b.Type {
+	case BindButton, BindHat:
+		if b.pressed(s) {
+			return 32767
+		}
+		return 0
+	case BindAxis:
+		if b.Range == FullAxis {
+			v := b.axisValue(s)
+			if trigger {
+				// a full axis trigger rests at its minimum
+				return (v + 32767) / 2
+			}
+			return v
+		}
+		v := b.halfAxis(s)
+		if trigger {
+			return v
+		}
+		// a half axis stick covers the whole output range
+		return v*2 - 32767
+	}
+	return 0
+}
+
+// axisValue returns the value of the bound axis, clamped to -32767..32767 and inverted if required
+func (b Binding) axisValue(s State) int {
+	if b.Index < 0 || b.Index >= len(s.AxisData) {
+		return 0
+	}
+	v := s.AxisData[b.Index]
+	if v > 32767 {
+		v = 32767
+	}
+	if v < -32767 {
+		v = -32767
+	}
+	if b.Invert {
+		v = -v
+	}
+	return v
+}
+
+// halfAxis returns the value of the bound half axis in the range 0..32767
+func (b Binding) halfAxis(s State) int {
+	v := b.axisValue(s)
+	switch b.Range {
+	case NegativeHalf:
+		v = -v
+	case FullAxis:
+		v = (v + 32767) / 2
+	}
+	if v < 0 {
+		return 0
+	}
+	return v
+}
+
+// hatDirections returns the directions of the hat whose horizontal axis is at index
+func hatDirections(s State, index int) int {
+	if index < 0 || index+1 >= len(s.AxisData) {
+		return 0
+	}
+	dirs := 0
+	x, y := s.AxisData[index], s.AxisData[index+1]
+	if x < -16384 {
+		dirs |= HatLeft
+	}
+	if x > 16384 {
+		dirs |= HatRight
+	}
+	if y < -16384 {
+		dirs |= HatUp
+	}
+	if y > 16384 {
+		dirs |= HatDown
+	}
+	return dirs
+}
+
+// DefaultGamepadMapping builds a mapping from the axis and button codes reported
+// by the joystick, following the layout used by linux gamepad drivers.
+// Returns false if the joystick does not report its codes or is not a gamepad
+func DefaultGamepadMapping(js Joystick) (*GamepadMapping, bool) {
+	if FindButton(js, ButtonSouth) < 0 {
+		return nil, false
+	}
+
+	m := &GamepadMapping{Name: js.Name()}
+
+	buttons := map[GamepadButton]ButtonCode{
+		GamepadSouth:         ButtonSouth,
+		GamepadEast:          ButtonEast,
+		GamepadWest:          ButtonWest,
+		GamepadNorth:         ButtonNorth,
+		GamepadLeftShoulder:  ButtonTL,
+		GamepadRightShoulder: ButtonTR,
+		GamepadBack:          ButtonSelect,
+		GamepadStart:         ButtonStart,
+		GamepadGuide:         ButtonMode,
+		GamepadLeftStick:     ButtonThumbL,
+		GamepadRightStick:    ButtonThumbR,
+		GamepadDPadUp:        ButtonDpadUp,
+		GamepadDPadDown:      ButtonDpadDown,
+		GamepadDPadLeft:      ButtonDpadLeft,
+		GamepadDPadRight:     ButtonDpadRight,
+	}
+	for button, code := range buttons {
+		if i := FindButton(js, code); i >= 0 {
+			m.Buttons[button] = Binding{Type: BindButton, Index: i}
+		}
+	}
+
+	// pads without d-pad buttons report the d-pad as the first hat
+	if hat := FindAxis(js, AxisHat0X); hat >= 0 && FindAxis(js, AxisHat0Y) == hat+1 {
+		hats := map[GamepadButton]int{
+			GamepadDPadUp:    HatUp,
+			GamepadDPadDown:  HatDown,
+			GamepadDPadLeft:  HatLeft,
+			GamepadDPadRight: HatRight,
+		}
+		for button, mask := range hats {
+			if m.Buttons[button].Type == BindNone {
+				m.Buttons[button] = Binding{Type: BindHat, Index: hat, HatMask: mask}
+			}
+		}
+	}
+
+	axes := map[GamepadAxis]AxisCode{
+		GamepadLeftX:        AxisX,
+		GamepadLeftY:        AxisY,
+		GamepadRightX:       AxisRX,
+		GamepadRightY:       AxisRY,
+		GamepadLeftTrigger:  AxisZ,
+		GamepadRightTrigger: AxisRZ,
+	}
+	for axis, code := range axes {
+		if i := FindAxis(js, code); i >= 0 {
+			m.Axes[axis] = Binding{Type: BindAxis, Index: i}
+		}
+	}
+
+	// pads with digital triggers report them as buttons
+	if m.Axes[GamepadLeftTrigger].Type == BindNone {
+		if i := FindButton(js, ButtonTL2); i >= 0 {
+			m.Axes[GamepadLeftTrigger] = Binding{Type: BindButton, Index: i}
+		}
+	}
+	if m.Axes[GamepadRightTrigger].Type == BindNone {
+		if i := FindButton(js, ButtonTR2); i >= 0 {
+			m.Axes[GamepadRightTrigger] = Binding{Type: BindButton, Index: i}
+		}
+	}
+
+	return m, true
+}
+
+// Gamepad presents a Joystick in the standard gamepad layout, using a GamepadMapping
+type Gamepad struct {
+	js      Joystick
+	mapping *GamepadMapping
+}
+
+// NewGamepad returns a Gamepad that reads js and arranges its state using mapping
+func NewGamepad(js Joystick, mapping *GamepadMapping) *Gamepad {
+	return &Gamepad{js, mapping}
+}
+
+// Joystick returns the underlying Joystick
+func (g *Gamepad) Joystick() Joystick {
+	return g.js
+}
+
+// Mapping returns the mapping used by the Gamepad
+func (g *Gamepad) Mapping() *GamepadMapping {
+	return g.mapping
+}
+
+// Read returns the current state of the gamepad
+func (g *Gamepad) Read() (GamepadState, error) {
+	state, err := g.js.Read()
+	if err != nil {
+		return GamepadState{}, err
+	}
+	return g.mapping.Map(state), nil
+}
@@ -0,0 +1,139 @@
+package joystick
+
+import (
+	"testing"
+)
+
+// mappedVirtual is a VirtualJoystick reporting the supplied axis and button codes
+type mappedVirtual struct {
+	*VirtualJoystick
+	axes    []AxisCode
+	buttons []ButtonCode
+}
+
+func newMappedVirtual(axes []AxisCode, buttons []ButtonCode) mappedVirtual {
+	return mappedVirtual{NewVirtualJoystick("pad", len(axes), len(buttons)), axes, buttons}
+}
+
+func (js mappedVirtual) AxisCodes() []AxisCode {
+	return js.axes
+}
+
+func (js mappedVirtual) ButtonCodes() []ButtonCode {
+	return js.buttons
+}
+
+func TestDefaultGamepadMapping(t *testing.T) {
+	js := newMappedVirtual(
+		[]AxisCode{AxisX, AxisY, AxisZ, AxisRX, AxisRY, AxisRZ},
+		[]ButtonCode{ButtonSouth, ButtonEast, ButtonNorth, ButtonWest, ButtonTL, ButtonTR, ButtonSelect, ButtonStart,
+			ButtonMode, ButtonThumbL, ButtonThumbR, ButtonDpadUp, ButtonDpadDown, ButtonDpadLeft, ButtonDpadRight})
+
+	m, ok := DefaultGamepadMapping(js)
+	if !ok {
+		t.Fatal("no mapping for a gamepad")
+	}
+	if m.Name != "pad" {
+		t.Errorf("got name %q", m.Name)
+	}
+
+	buttons := [GamepadButtonCount]int{0, 1, 3, 2, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14}
+	for button, index := range buttons {
+		if want := (Binding{Type: BindButton, Index: index}); m.Buttons[button] != want {
+			t.Errorf("button %d bound to %+v, want %+v", button, m.Buttons[button], want)
+		}
+	}
+	axes := [GamepadAxisCount]int{0, 1, 3, 4, 2, 5}
+	for axis, index := range axes {
+		if want := (Binding{Type: BindAxis, Index: index}); m.Axes[axis] != want {
+			t.Errorf("axis %d bound to %+v, want %+v", axis, m.Axes[axis], want)
+		}
+	}
+
+	// triggers rest at the minimum of their axis
+	js.SetAxis(2, MinAxisValue)
+	js.SetAxis(5, MaxAxisValue)
+	js.Press(2)
+	g, err := NewGamepad(js, m).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.LeftTrigger() != 0 || g.RightTrigger() != MaxAxisValue || !g.Pressed(GamepadNorth) || g.Pressed(GamepadWest) {
+		t.Errorf("got %+v", g)
+	}
+}
+
+func TestDefaultGamepadMappingFallbacks(t *testing.T) {
+	// the d-pad reported as a hat, and digital triggers
+	js := newMappedVirtual(
+		[]AxisCode{AxisX, AxisY, AxisHat0X, AxisHat0Y},
+		[]ButtonCode{ButtonSouth, ButtonEast, ButtonTL2, ButtonTR2})
+
+	m, ok := DefaultGamepadMapping(js)
+	if !ok {
+		t.Fatal("no mapping for a gamepad")
+	}
+	hats := map[GamepadButton]int{
+		GamepadDPadUp:    HatUp,
+		GamepadDPadDown:  HatDown,
+		GamepadDPadLeft:  HatLeft,
+		GamepadDPadRight: HatRight,
+	}
+	for button, mask := range hats {
+		if want := (Binding{Type: BindHat, Index: 2, HatMask: mask}); m.Buttons[button] != want {
+			t.Errorf("button %d bound to %+v, want %+v", button, m.Buttons[button], want)
+		}
+	}
+	if want := (Binding{Type: BindButton, Index: 2}); m.Axes[GamepadLeftTrigger] != want {
+		t.Errorf("left trigger bound to %+v, want %+v", m.Axes[GamepadLeftTrigger], want)
+	}
+	if want := (Binding{Type: BindButton, Index: 3}); m.Axes[GamepadRightTrigger] != want {
+		t.Errorf("right trigger bound to %+v, want %+v", m.Axes[GamepadRightTrigger], want)
+	}
+	if m.Axes[GamepadRightX].Type != BindNone || m.Buttons[GamepadNorth].Type != BindNone {
+		t.Error("inputs the joystick does not have are bound")
+	}
+
+	js.SetAxis(2, MinAxisValue)
+	js.SetAxis(3, MinAxisValue)
+	js.Press(2)
+	g, err := NewGamepad(js, m).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x, y := g.DPad(); x != -1 || y != -1 {
+		t.Errorf("got d-pad %d, %d, want up left", x, y)
+	}
+	if g.LeftTrigger() != MaxAxisValue || g.RightTrigger() != 0 {
+		t.Errorf("got triggers %d, %d", g.LeftTrigger(), g.RightTrigger())
+	}
+
+	// a hat whose vertical axis does not follow the horizontal one is not used
+	js = newMappedVirtual([]AxisCode{AxisHat0X, AxisX, AxisHat0Y}, []ButtonCode{ButtonSouth})
+	if m, _ := DefaultGamepadMapping(js); m.Buttons[GamepadDPadUp].Type != BindNone {
+		t.Errorf("d-pad bound to %+v", m.Buttons[GamepadDPadUp])
+	}
+}
+
+func TestDefaultGamepadMappingNotGamepad(t *testing.T) {
+	if _, ok := DefaultGamepadMapping(newMappedVirtual([]AxisCode{AxisX}, []ButtonCode{ButtonTrigger})); ok {
+		t.Error("mapping for a joystick without ButtonSouth")
+	}
+	if _, ok := DefaultGamepadMapping(NewVirtualJoystick("pad", 2, 2)); ok {
+		t.Error("mapping for a joystick without codes")
+	}
+}
+
+func TestGamepadStatePressed(t *testing.T) {
+	var g GamepadState
+	g.Buttons[GamepadSouth] = true
+
+	if !g.Pressed(GamepadSouth) || g.Pressed(GamepadEast) {
+		t.Error("wrong button state")
+	}
+	for _, button := range []GamepadButton{-1, GamepadButtonCount, 100} {
+		if g.Pressed(button) {
+			t.Errorf("button %d pressed", button)
+		}
+	}
+}
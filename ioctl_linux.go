@@ -43,6 +43,48 @@ func _IOW(t int, nr int, size int) int {
 }
 
 func ioctl(f *os.File, req int, ptr unsafe.Pointer) syscall.Errno {
-	_, _, err := unix.Syscall(unix.SYS_IOCTL, uintptr(f.Fd()), uintptr(req), uintptr(ptr))
-	return err
+	var errno syscall.Errno
+	if err := control(f, func(fd uintptr) {
+		_, _, errno = unix.Syscall(unix.SYS_IOCTL, fd, uintptr(req), uintptr(ptr))
+	}); err != 0 {
+		return err
+	}
+	return errno
+}
+
+func ioctlInt(f *os.File, req int, val int) syscall.Errno {
+	var errno syscall.Errno
+	if err := control(f, func(fd uintptr) {
+		_, _, errno = unix.Syscall(unix.SYS_IOCTL, fd, uintptr(req), uintptr(val))
+	}); err != 0 {
+		return err
+	}
+	return errno
+}
+
+// control calls fn with the file descriptor of f. Unlike f.Fd(), it leaves the descriptor
+// non-blocking, so that closing f still wakes up a pending Read.
+// Returns EBADF if f is closed
+func control(f *os.File, fn func(fd uintptr)) syscall.Errno {
+	rc, err := f.SyscallConn()
+	if err == nil {
+		err = rc.Control(fn)
+	}
+	if err != nil {
+		return syscall.EBADF
+	}
+	return 0
+}
+
+// deviceFile gives access to the ioctls of an open device node
+type deviceFile struct {
+	*os.File
+}
+
+func (f deviceFile) ioctl(req int, ptr unsafe.Pointer) syscall.Errno {
+	return ioctl(f.File, req, ptr)
+}
+
+func (f deviceFile) ioctlInt(req int, val int) syscall.Errno {
+	return ioctlInt(f.File, req, val)
 }
@@ -25,12 +25,165 @@
 //
 package joystick
 
+import (
+	"time"
+)
+
 // State holds the current state of the joystick
 type State struct {
-	// Value of each axis as an integer in the range -32767 to 32768
+	// Value of each axis as an integer in the range -32767 to 32767, 0 being centered.
+	// Axis and Trigger return the values normalized
 	AxisData []int
-	// The state of each button as a bit in a 32 bit integer. 1 = pressed, 0 = not pressed
+	// The state of the first 32 buttons as a bit in a 32 bit integer. 1 = pressed, 0 = not pressed
 	Buttons uint32
+	// The state of every button. true = pressed
+	ButtonData []bool
+	// Time of the last change to the state, as seen by this process
+	Timestamp time.Time
+	// Incremented on every change to the state. If two reads return the same
+	// Sequence nothing changed in between
+	Sequence uint64
+	// Number of times each button was pressed since the previous Read.
+	// May be shorter than ButtonData, or empty; use PressCount
+	Presses []int
+	// Number of times each button was released since the previous Read.
+	// May be shorter than ButtonData, or empty; use JustReleased
+	Releases []int
+}
+
+// Pressed returns true if the specified button is pressed
+func (s State) Pressed(button int) bool {
+	if button >= 0 && button < len(s.ButtonData) {
+		return s.ButtonData[button]
+	}
+	if button >= 0 && button < 32 {
+		return s.Buttons&(1<<uint(button)) != 0
+	}
+	return false
+}
+
+// PressedCount returns the number of buttons currently pressed
+func (s State) PressedCount() int {
+	count := 0
+	for button := range s.ButtonData {
+		if s.ButtonData[button] {
+			count++
+		}
+	}
+	return count
+}
+
+// JustPressed returns true if the specified button was pressed since the previous Read,
+// even if it has been released again
+func (s State) JustPressed(button int) bool {
+	return s.PressCount(button) > 0
+}
+
+// JustReleased returns true if the specified button was released since the previous Read,
+// even if it has been pressed again
+func (s State) JustReleased(button int) bool {
+	return edgeCount(s.Releases, button) > 0
+}
+
+// PressCount returns the number of times the specified button was pressed since the previous Read
+func (s State) PressCount(button int) int {
+	return edgeCount(s.Presses, button)
+}
+
+func edgeCount(counts []int, button int) int {
+	if button < 0 || button >= len(counts) {
+		return 0
+	}
+	return counts[button]
+}
+
+// updateButton updates the state of a button like setButton, and counts the press or release.
+// Returns true if the state of the button changed
+func (s *State) updateButton(button int, pressed bool) bool {
+	if !s.setButton(button, pressed) {
+		return false
+	}
+	n := len(s.ButtonData)
+	if n < 32 {
+		n = 32
+	}
+	if button >= n {
+		return true
+	}
+	if pressed {
+		if s.Presses == nil {
+			s.Presses = make([]int, n)
+		}
+		s.Presses[button]++
+	} else {
+		if s.Releases == nil {
+			s.Releases = make([]int, n)
+		}
+		s.Releases[button]++
+	}
+	return true
+}
+
+// clearEdges restarts the counts of presses and releases
+func (s *State) clearEdges() {
+	for i := range s.Presses {
+		s.Presses[i] = 0
+	}
+	for i := range s.Releases {
+		s.Releases[i] = 0
+	}
+}
+
+// copyInto copies s to dst, reusing the slices of dst when they are large enough.
+// dst shares no memory with s afterwards
+func (s *State) copyInto(dst *State) {
+	axisData, buttonData, presses, releases := dst.AxisData, dst.ButtonData, dst.Presses, dst.Releases
+	*dst = *s
+	dst.AxisData = append(axisData[:0], s.AxisData...)
+	dst.ButtonData = append(buttonData[:0], s.ButtonData...)
+	dst.Presses = append(presses[:0], s.Presses...)
+	dst.Releases = append(releases[:0], s.Releases...)
+}
+
+// readInto implements StateReader for the backends: copies s to dst and restarts the counts
+// of presses and releases
+func (s *State) readInto(dst *State) {
+	s.copyInto(dst)
+	s.clearEdges()
+}
+
+// setButton updates the state of a button in both ButtonData and Buttons.
+// Returns true if the state of the button changed
+func (s *State) setButton(button int, pressed bool) bool {
+	if button < 0 || s.Pressed(button) == pressed {
+		return false
+	}
+	if button < len(s.ButtonData) {
+		s.ButtonData[button] = pressed
+	}
+	if button < 32 {
+		if pressed {
+			s.Buttons |= 1 << uint(button)
+		} else {
+			s.Buttons &= ^(1 << uint(button))
+		}
+	}
+	return true
+}
+
+// setAxis updates the value of an axis. Returns true if the value changed
+func (s *State) setAxis(axis int, value int) bool {
+	if axis < 0 || axis >= len(s.AxisData) || s.AxisData[axis] == value {
+		return false
+	}
+	s.AxisData[axis] = value
+	return true
+}
+
+// markChanged records that the state changed at time t
+func (s *State) markChanged(t time.Time) {
+	s.Sequence++
+	s.Timestamp = t
 }
 
 // Interface Joystick provides access to the Joystick opened with the Open() function
@@ -41,9 +194,108 @@ type Joystick interface {
 	ButtonCount() int
 	// Name returns the string name of this Joystick
 	Name() string
-	// Read returns the current State of the joystick.
+	// Read returns the current State of the joystick. The State does not share memory
+	// with the joystick, and is not changed by later reads.
 	// On an error condition (for example, joystick has been unplugged) error is not nil
 	Read() (State, error)
-	// Close releases this joystick resource
-	Close()
+	// Close releases this joystick resource, and waits for any background reader to exit.
+	// Calling Close again has no effect; Read returns ErrClosed once the joystick is closed
+	Close() error
+}
+
+// Interface StateReader is implemented by joysticks that can read their state without allocating
+type StateReader interface {
+	// ReadInto is like Read, but stores the State in state, reusing its slices so
+	// that no memory is allocated once they are large enough
+	ReadInto(state *State) error
+}
+
+// ReadInto reads the state of js into state, like Read, reusing the slices of state.
+// Joysticks that do not implement StateReader are read with Read, and their State copied
+func ReadInto(js Joystick, state *State) error {
+	if sr, ok := js.(StateReader); ok {
+		return sr.ReadInto(state)
+	}
+	s, err := js.Read()
+	s.copyInto(state)
+	return err
+}
+
+// DeviceInfo describes an attached joystick, as returned by Enumerate()
+type DeviceInfo struct {
+	// The id to pass to Open()
+	ID int
+	// Path of the device node. Only set under linux
+	Path string
+	// Path of the matching evdev device node, if any. Only set under linux
+	EventPath string
+	// Name of the device
+	Name string
+	// Serial number or other unique identifier of the device, if it reports one
+	Serial string
+	// Bus the device is attached to, using the linux BUS_ constants
+	BusType uint16
+	// USB style vendor id
+	Vendor uint16
+	// USB style product id
+	Product uint16
+	// Version of the device
+	Version uint16
+	// Number of axis
+	AxisCount int
+	// Number of buttons
+	ButtonCount int
+	// Version of the driver, where available
+	DriverVersion uint32
+}
+
+// DeviceEventType identifies whether a DeviceEvent reports a connection or a disconnection
+type DeviceEventType int
+
+const (
+	// DeviceAdded is reported when a joystick is connected
+	DeviceAdded DeviceEventType = iota + 1
+	// DeviceRemoved is reported when a joystick is disconnected
+	DeviceRemoved
+)
+
+// DeviceEvent is sent by a Watcher when a joystick is connected or disconnected
+type DeviceEvent struct {
+	Type DeviceEventType
+	// Information on the device. For removed devices this is the information
+	// collected when the device was added
+	Info DeviceInfo
+}
+
+// InputID holds the identity of a device, as reported by the Linux input subsystem
+type InputID struct {
+	BusType uint16
+	Vendor  uint16
+	Product uint16
+	Version uint16
+}
+
+// AbsInfo describes the range of an absolute axis, as reported by the Linux input subsystem
+type AbsInfo struct {
+	// Last reported raw value
+	Value int32
+	// Minimum raw value
+	Minimum int32
+	// Maximum raw value
+	Maximum int32
+	// Noise filtered out by the driver
+	Fuzz int32
+	// Size of the dead zone around the center
+	Flat int32
+	// Resolution in units per millimeter, or units per radian for rotational axes
+	Resolution int32
+}
+
+// Interface EvdevJoystick provides access to a Joystick opened with the OpenEvdev() function
+type EvdevJoystick interface {
+	Joystick
+	// InputID returns the bus type, vendor, product and version of the device
+	InputID() InputID
+	// AbsInfo returns the raw range of the specified axis
+	AbsInfo(axis int) AbsInfo
 }
@@ -0,0 +1,40 @@
+package joystick
+
+import (
+	"reflect"
+	"testing"
+)
+
+// plainJoystick hides the optional interfaces of the joystick it wraps
+type plainJoystick struct {
+	Joystick
+}
+
+func TestReadInto(t *testing.T) {
+	vj := NewVirtualJoystick("pad", 2, 2)
+	vj.SetAxis(1, 1000)
+	vj.Press(1)
+
+	for _, js := range []Joystick{plainJoystick{vj}, vj} {
+		// the slices of state are reused when large enough
+		state := State{AxisData: make([]int, 1, 8), Presses: []int{5}}
+		axisData := state.AxisData[:1]
+		if err := ReadInto(js, &state); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(state.AxisData, []int{0, 1000}) || !reflect.DeepEqual(state.ButtonData, []bool{false, true}) {
+			t.Errorf("%T: got axis %v and buttons %v", js, state.AxisData, state.ButtonData)
+		}
+		if &state.AxisData[0] != &axisData[0] {
+			t.Errorf("%T: axis data not reused", js)
+		}
+		vj.Release(1)
+		vj.Press(1)
+	}
+
+	vj.Close()
+	var state State
+	if err := ReadInto(plainJoystick{vj}, &state); err != ErrClosed {
+		t.Errorf("got %v after Close, want ErrClosed", err)
+	}
+}
@@ -75,3 +75,32 @@ void closeHIDManager(IOHIDManagerRef manager) {
 	CFRelease(manager);
 }
 
+
+static int getIntProperty(IOHIDDeviceRef device, CFStringRef key) {
+	int value = 0;
+	CFTypeRef ref = IOHIDDeviceGetProperty(device, key);
+	if (ref && CFGetTypeID(ref) == CFNumberGetTypeID()) {
+		CFNumberGetValue((CFNumberRef) ref, kCFNumberIntType, &value);
+	}
+	return value;
+}
+
+int getVendorID(IOHIDDeviceRef device) {
+	return getIntProperty(device, CFSTR(kIOHIDVendorIDKey));
+}
+
+int getProductID(IOHIDDeviceRef device) {
+	return getIntProperty(device, CFSTR(kIOHIDProductIDKey));
+}
+
+int getVersionNumber(IOHIDDeviceRef device) {
+	return getIntProperty(device, CFSTR(kIOHIDVersionNumberKey));
+}
+
+void getProductName(IOHIDDeviceRef device, char *buffer, int len) {
+	buffer[0] = 0;
+	CFTypeRef ref = IOHIDDeviceGetProperty(device, CFSTR(kIOHIDProductKey));
+	if (ref && CFGetTypeID(ref) == CFStringGetTypeID()) {
+		CFStringGetCString((CFStringRef) ref, buffer, len, kCFStringEncodingUTF8);
+	}
+}
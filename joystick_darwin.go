@@ -9,13 +9,19 @@ extern void removeCallback(void* ctx, IOReturn res, void *sender);
 extern IOHIDManagerRef openHIDManager();
 extern void closeHIDManager(IOHIDManagerRef manager);
 extern void addHIDElement(void *value, void *parameter);
+extern int getVendorID(IOHIDDeviceRef device);
+extern int getProductID(IOHIDDeviceRef device);
+extern int getVersionNumber(IOHIDDeviceRef device);
+extern void getProductName(IOHIDDeviceRef device, char *buffer, int len);
 #define kCFRunLoopMode CFSTR("go-joystick")
 */
 import "C"
 
 import (
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 	"unsafe"
 )
 
@@ -55,9 +61,15 @@ func addCallback(ctx unsafe.Pointer, res C.IOReturn, sender unsafe.Pointer, devi
 	}
 	id := mgr.deviceCnt
 	mgr.deviceCnt++
+	var name [256]C.char
+	C.getProductName(device, &name[0], C.int(len(name)))
 	impl := &joystickImpl{
-		id:  id,
-		ref: device,
+		id:      id,
+		ref:     device,
+		name:    C.GoString(&name[0]),
+		vendor:  uint16(C.getVendorID(device)),
+		product: uint16(C.getProductID(device)),
+		version: uint16(C.getVersionNumber(device)),
 	}
 	mgr.devices[id] = impl
 	C.IOHIDDeviceRegisterRemovalCallback(device, C.IOHIDCallback(C.removeCallback), unsafe.Pointer(impl))
@@ -105,12 +117,10 @@ func (js *joystickImpl) addElements(elems C.CFArrayRef) {
 						continue
 					}
 					js.axes = append(js.axes, &joystickAxis{
-						ref:    elem,
-						min:    int(C.IOHIDElementGetLogicalMin(elem)),
-						max:    int(C.IOHIDElementGetLogicalMax(elem)),
-						center: -1,
+						ref: elem,
+						min: int(C.IOHIDElementGetLogicalMin(elem)),
+						max: int(C.IOHIDElementGetLogicalMax(elem)),
 					})
-					js.state.AxisData = append(js.state.AxisData, 0)
 				case C.kHIDUsage_GD_Hatswitch:
 					if js.contains(elem) {
 						continue
@@ -118,7 +128,6 @@ func (js *joystickImpl) addElements(elems C.CFArrayRef) {
 					js.hats = append(js.hats, &joystickHat{
 						ref: elem,
 					})
-					js.state.AxisData = append(js.state.AxisData, 0, 0)
 				}
 			case C.kHIDPage_Button:
 				if js.contains(elem) {
@@ -164,10 +173,9 @@ func (mgr *joystickManager) Close() {
 // -- elem
 
 type joystickAxis struct {
-	ref    C.IOHIDElementRef
-	min    int
-	max    int
-	center int
+	ref C.IOHIDElementRef
+	min int
+	max int
 }
 
 type joystickButton struct {
@@ -180,14 +188,27 @@ type joystickHat struct {
 
 // -- impl
 
+// joystickImpl is an attached device. It is shared by every joystickHandle opened on it
 type joystickImpl struct {
 	id      int
 	ref     C.IOHIDDeviceRef
+	name    string
+	vendor  uint16
+	product uint16
+	version uint16
 	removed bool
 	axes    []*joystickAxis
 	hats    []*joystickHat
 	buttons []*joystickButton
-	state   State
+}
+
+// joystickHandle is the Joystick returned by Open. Each handle has its own state,
+// so that closing or reading one does not affect the others opened on the same device
+type joystickHandle struct {
+	dev    *joystickImpl
+	mutex  sync.Mutex
+	closed bool
+	state  State
 }
 
 func Open(id int) (Joystick, error) {
@@ -195,14 +216,46 @@ func Open(id int) (Joystick, error) {
 	defer mgrMutex.Unlock()
 	mgr := openManager()
 	if mgr == nil {
-		return nil, fmt.Errorf("Could not open joystick manager")
+		return nil, &OpenError{Op: "IOHIDManagerOpen", Err: fmt.Errorf("Could not open joystick manager")}
 	}
 	js := mgr.devices[id]
-	if js == nil {
-		return nil, fmt.Errorf("Device not found")
+	if js == nil || js.removed {
+		return nil, &OpenError{Op: "open", Err: ErrNotFound}
 	}
 	mgr.deviceUsed++
-	return js, nil
+	h := &joystickHandle{dev: js}
+	h.state.AxisData = make([]int, js.AxisCount())
+	h.state.ButtonData = make([]bool, js.ButtonCount())
+	return h, nil
+}
+
+// Enumerate returns information on every joystick currently attached, sorted by id
+func Enumerate() ([]DeviceInfo, error) {
+	mgrMutex.Lock()
+	defer mgrMutex.Unlock()
+	mgr := openManager()
+	if mgr == nil {
+		return nil, fmt.Errorf("Could not open joystick manager")
+	}
+	devices := []DeviceInfo{}
+	for id, js := range mgr.devices {
+		if js.removed {
+			continue
+		}
+		devices = append(devices, DeviceInfo{
+			ID:          id,
+			Name:        js.name,
+			Vendor:      js.vendor,
+			Product:     js.product,
+			Version:     js.version,
+			AxisCount:   js.AxisCount(),
+			ButtonCount: js.ButtonCount(),
+		})
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].ID < devices[j].ID
+	})
+	return devices, nil
 }
 
 func (js *joystickImpl) AxisCount() int {
@@ -214,30 +267,49 @@ func (js *joystickImpl) ButtonCount() int {
 }
 
 func (js *joystickImpl) Name() string {
-	return ""
+	return js.name
 }
 
-func (js *joystickImpl) Read() (State, error) {
+func (h *joystickHandle) AxisCount() int {
+	return h.dev.AxisCount()
+}
+
+func (h *joystickHandle) ButtonCount() int {
+	return h.dev.ButtonCount()
+}
+
+func (h *joystickHandle) Name() string {
+	return h.dev.Name()
+}
+
+func (h *joystickHandle) Read() (State, error) {
+	var state State
+	err := h.ReadInto(&state)
+	return state, err
+}
+
+func (h *joystickHandle) ReadInto(state *State) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	js := h.dev
+	if h.closed {
+		h.state.readInto(state)
+		return ErrClosed
+	}
+	if js.removed {
+		h.state.readInto(state)
+		return ErrDisconnected
+	}
+	changed := false
 	for idx, axe := range js.axes {
 		var valueRef C.IOHIDValueRef
 		if C.IOHIDDeviceGetValue(js.ref, axe.ref, &valueRef) != C.kIOReturnSuccess {
 			continue
 		}
-		min := -32767
-		max := 32768
-		value := int(C.IOHIDValueGetIntegerValue(valueRef))
-		if axe.center < 0 {
-			axe.center = value
-			js.state.AxisData[idx] = 0
-		} else if axe.center != int(0.5+float64(axe.max-axe.min)/2.0) {
-			js.state.AxisData[idx] = int(float64(value-axe.min)*float64(max-min)/float64(axe.max-axe.min)) + min
-		} else {
-			if value < axe.center {
-				js.state.AxisData[idx] = int(float64(value-axe.min)*float64(0-min)/float64(axe.center-axe.min)) + min
-			} else {
-				js.state.AxisData[idx] = int(float64(value-axe.center)*float64(max-0)/float64(axe.max-axe.center)) + 0
-			}
-		}
+		value := int64(C.IOHIDValueGetIntegerValue(valueRef))
+		axis := ScaleAxis(value, int64(axe.min), int64(axe.max))
+		changed = h.state.setAxis(idx, axis) || changed
 	}
 	for idx, hat := range js.hats {
 		stateIdxX := len(js.axes) + idx*2
@@ -248,55 +320,45 @@ func (js *joystickImpl) Read() (State, error) {
 			continue
 		}
 
-		value := int(int(C.IOHIDValueGetIntegerValue(valueRef)))
-
-		if value == 8 {
-			js.state.AxisData[stateIdxX] = 0
-			js.state.AxisData[stateIdxY] = 0
-			continue
-		}
-		if value == 0 || value == 4 {
-			js.state.AxisData[stateIdxX] = 0
-		} else if value < 4 {
-			js.state.AxisData[stateIdxX] = 32768
-		} else {
-			js.state.AxisData[stateIdxX] = -32767
-		}
+		x, y := hatAxes(int(C.IOHIDValueGetIntegerValue(valueRef)))
 
-		if value == 2 || value == 6 {
-			js.state.AxisData[stateIdxY] = 0
-		} else if value > 2 && value < 6 {
-			js.state.AxisData[stateIdxY] = 32768
-		} else {
-			js.state.AxisData[stateIdxY] = -32767
-		}
+		changed = h.state.setAxis(stateIdxX, x) || changed
+		changed = h.state.setAxis(stateIdxY, y) || changed
 	}
-	buttons := uint32(0)
 	for idx, btn := range js.buttons {
 		var valueRef C.IOHIDValueRef
 		if C.IOHIDDeviceGetValue(js.ref, btn.ref, &valueRef) != C.kIOReturnSuccess {
 			continue
 		}
-		if int(C.IOHIDValueGetIntegerValue(valueRef)) > 0 {
-			buttons |= uint32(1) << uint(idx)
-		}
+		changed = h.state.updateButton(idx, int(C.IOHIDValueGetIntegerValue(valueRef)) > 0) || changed
+	}
+	if changed {
+		h.state.markChanged(time.Now())
 	}
-	js.state.Buttons = buttons
-	return js.state, nil
+	h.state.readInto(state)
+	return nil
 }
 
-func (js *joystickImpl) Close() {
+// Close closes this handle only. The device stays open for the other handles
+func (h *joystickHandle) Close() error {
 	mgrMutex.Lock()
 	defer mgrMutex.Unlock()
-	mgr := openManager()
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.closed {
+		return nil
+	}
+	h.closed = true
+	// the last Close releases the package level manager, so that the next Open creates a new one
 	if mgr == nil {
-		return
+		return nil
 	}
 	mgr.deviceUsed--
 	if mgr.deviceUsed == 0 {
 		mgr.Close()
 		mgr = nil
 	}
+	return nil
 }
 
 func (js *joystickImpl) contains(ref C.IOHIDElementRef) bool {
@@ -0,0 +1,386 @@
+// +build linux
+
+package joystick
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	_EV_SYN uint16 = 0x00
+	_EV_KEY uint16 = 0x01
+	_EV_ABS uint16 = 0x03
+	_EV_MAX        = 0x1f
+
+	_SYN_REPORT  uint16 = 0x00
+	_SYN_DROPPED uint16 = 0x03
+
+	_KEY_MAX      = 0x2ff
+	_ABS_MAX      = 0x3f
+	_BTN_MISC     = 0x100
+	_BTN_JOYSTICK = 0x120
+
+	_INPUT_EVENT_SIZE  = int(unsafe.Sizeof(inputEvent{})) /* size of struct input_event */
+	_INPUT_EVENT_BATCH = 64                               /* number of events read at once */
+)
+
+var (
+	_EVIOCGVERSION = _IOR('E', 0x01, 4)  /* get driver version */
+	_EVIOCGID      = _IOR('E', 0x02, 8)  /* get device ID */
+	_EVIOCGNAME    = func(len int) int { /* get device name */
+		return _IOC(_IOC_READ, 'E', 0x06, len)
+	}
+	_EVIOCGKEY = func(len int) int { /* get global key state */
+		return _IOC(_IOC_READ, 'E', 0x18, len)
+	}
+	_EVIOCGBIT = func(ev int, len int) int { /* get event bits */
+		return _IOC(_IOC_READ, 'E', 0x20+ev, len)
+	}
+	_EVIOCGABS = func(abs int) int { /* get abs value/limits */
+		return _IOR('E', 0x40+abs, int(unsafe.Sizeof(AbsInfo{})))
+	}
+)
+
+// inputEvent mirrors struct input_event
+type inputEvent struct {
+	Time  unix.Timeval
+	Type  uint16
+	Code  uint16
+	Value int32
+}
+
+// evdevDevice is the device node an evdevSource reads. Tests replace it with a fake
+type evdevDevice interface {
+	io.Reader
+	ioctl(req int, ptr unsafe.Pointer) syscall.Errno
+}
+
+// evdevSource reads input_event structs from a /dev/input/eventN device and
+// translates them to joystick axis and button events
+type evdevSource struct {
+	dev     evdevDevice
+	batch   batchReader
+	absInfo []AbsInfo
+	axisIdx [_ABS_MAX + 1]int
+	btnIdx  [_KEY_MAX + 1]int
+	// last value reported for each axis and button, to find what changed while events were dropped
+	axes    []int
+	buttons []bool
+	dropped bool
+	pending []Event
+}
+
+type evdevJoystick struct {
+	*joystickImpl
+	source *evdevSource
+	id     InputID
+}
+
+// OpenEvdev opens the Joystick for reading through the Linux evdev interface.
+//
+// The id is used to construct the device name:
+//   for example: id 0 will open device: "/dev/input/event0"
+//
+// Unlike Open(), the returned EvdevJoystick reports the real range of each axis
+// and the identity of the device. Axes and buttons are numbered in the same
+// order as the joydev interface, and axis values are scaled to the same range.
+func OpenEvdev(id int) (EvdevJoystick, error) {
+	path := filepath.Join(devInputRoot, fmt.Sprintf("event%d", id))
+	f, err := os.OpenFile(path, os.O_RDONLY, 0666)
+
+	if err != nil {
+		return nil, newOpenError(path, "open", err)
+	}
+
+	js, err := newEvdevJoystick(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	js.start()
+
+	return js, nil
+}
+
+func newEvdevJoystick(f *os.File) (*evdevJoystick, error) {
+	var inputID InputID
+	var version int32
+	var buffer [256]byte
+	var evBits [(_EV_MAX + 8) / 8]byte
+	var absBits [(_ABS_MAX + 8) / 8]byte
+	var keyBits [(_KEY_MAX + 8) / 8]byte
+	var keyState [(_KEY_MAX + 8) / 8]byte
+
+	if ioerr := ioctl(f, _EVIOCGVERSION, unsafe.Pointer(&version)); ioerr != 0 {
+		return nil, newOpenError(f.Name(), "EVIOCGVERSION", ioerr)
+	}
+
+	if ioerr := ioctl(f, _EVIOCGID, unsafe.Pointer(&inputID)); ioerr != 0 {
+		return nil, newOpenError(f.Name(), "EVIOCGID", ioerr)
+	}
+
+	if ioerr := ioctl(f, _EVIOCGNAME(len(buffer)-1), unsafe.Pointer(&buffer)); ioerr != 0 {
+		return nil, newOpenError(f.Name(), "EVIOCGNAME", ioerr)
+	}
+
+	if ioerr := ioctl(f, _EVIOCGBIT(0, len(evBits)), unsafe.Pointer(&evBits)); ioerr != 0 {
+		return nil, newOpenError(f.Name(), "EVIOCGBIT", ioerr)
+	}
+
+	if testBit(evBits[:], int(_EV_ABS)) {
+		if ioerr := ioctl(f, _EVIOCGBIT(int(_EV_ABS), len(absBits)), unsafe.Pointer(&absBits)); ioerr != 0 {
+			return nil, newOpenError(f.Name(), "EVIOCGBIT", ioerr)
+		}
+	}
+
+	if testBit(evBits[:], int(_EV_KEY)) {
+		if ioerr := ioctl(f, _EVIOCGBIT(int(_EV_KEY), len(keyBits)), unsafe.Pointer(&keyBits)); ioerr != 0 {
+			return nil, newOpenError(f.Name(), "EVIOCGBIT", ioerr)
+		}
+		if ioerr := ioctl(f, _EVIOCGKEY(len(keyState)), unsafe.Pointer(&keyState)); ioerr != 0 {
+			return nil, newOpenError(f.Name(), "EVIOCGKEY", ioerr)
+		}
+	}
+
+	if !hasJoystickInputs(absBits[:], keyBits[:]) {
+		// keyboards, mice and other input devices share the evdev interface
+		return nil, &OpenError{Path: f.Name(), Op: "open", Err: ErrNotJoystick}
+	}
+
+	src, err := newEvdevSource(deviceFile{f}, absBits[:], keyBits[:], keyState[:])
+	if err != nil {
+		return nil, newOpenError(f.Name(), "EVIOCGABS", err)
+	}
+
+	axisCodes := make([]AxisCode, len(src.axes))
+	for code, axis := range src.axisIdx {
+		if axis >= 0 {
+			axisCodes[axis] = AxisCode(code)
+		}
+	}
+	buttonCodes := make([]ButtonCode, len(src.buttons))
+	for code, button := range src.btnIdx {
+		if button >= 0 {
+			buttonCodes[button] = ButtonCode(code)
+		}
+	}
+
+	js := &joystickImpl{}
+	js.axisCount = len(src.axes)
+	js.buttonCount = len(src.buttons)
+	js.file = f
+	js.closer = f
+	js.source = src
+	js.name = string(bytes.TrimRight(buffer[:], "\x00"))
+	js.axisCodes = axisCodes
+	js.buttonCodes = buttonCodes
+	js.state.AxisData = append([]int(nil), src.axes...)
+	js.state.ButtonData = make([]bool, len(src.buttons))
+	for button, pressed := range src.buttons {
+		js.state.setButton(button, pressed)
+	}
+	// the initial state was read above, there are no init events to wait for
+	js.ready = true
+
+	return &evdevJoystick{js, src, inputID}, nil
+}
+
+// newEvdevSource returns a source reading dev, for the axes set in absBits and the buttons set
+// in keyBits. keyState holds the buttons pressed, the value of each axis is read from dev
+func newEvdevSource(dev evdevDevice, absBits, keyBits, keyState []byte) (*evdevSource, error) {
+	src := &evdevSource{dev: dev, batch: newBatchReader(dev, _INPUT_EVENT_SIZE, _INPUT_EVENT_BATCH)}
+
+	for i := range src.axisIdx {
+		src.axisIdx[i] = -1
+		if !testBit(absBits, i) {
+			continue
+		}
+		var info AbsInfo
+		if ioerr := dev.ioctl(_EVIOCGABS(i), unsafe.Pointer(&info)); ioerr != 0 {
+			return nil, ioerr
+		}
+		src.axisIdx[i] = len(src.absInfo)
+		src.absInfo = append(src.absInfo, info)
+		src.axes = append(src.axes, ScaleAxis(int64(info.Value), int64(info.Minimum), int64(info.Maximum)))
+	}
+
+	// buttons are numbered the same way as joydev: BTN_JOYSTICK and above first, then BTN_MISC upwards
+	for i := range src.btnIdx {
+		src.btnIdx[i] = -1
+	}
+	for _, r := range [][2]int{{_BTN_JOYSTICK, _KEY_MAX}, {_BTN_MISC, _BTN_JOYSTICK - 1}} {
+		for i := r[0]; i <= r[1]; i++ {
+			if !testBit(keyBits, i) {
+				continue
+			}
+			src.btnIdx[i] = len(src.buttons)
+			src.buttons = append(src.buttons, testBit(keyState, i))
+		}
+	}
+	return src, nil
+}
+
+// hasJoystickInputs returns true if the device has an absolute axis, or a button numbered
+// BTN_MISC or above. Keyboard keys are below BTN_MISC
+func hasJoystickInputs(absBits, keyBits []byte) bool {
+	for i := 0; i <= _ABS_MAX; i++ {
+		if testBit(absBits, i) {
+			return true
+		}
+	}
+	for i := _BTN_MISC; i <= _KEY_MAX; i++ {
+		if testBit(keyBits, i) {
+			return true
+		}
+	}
+	return false
+}
+
+func (js *evdevJoystick) InputID() InputID {
+	return js.id
+}
+
+func (js *evdevJoystick) AbsInfo(axis int) AbsInfo {
+	return js.source.absInfo[axis]
+}
+
+func (e *evdevSource) readEvent() (Event, error) {
+	for {
+		if len(e.pending) > 0 {
+			ev := e.pending[0]
+			e.pending = e.pending[1:]
+			return ev, nil
+		}
+
+		ie, err := e.next()
+		if err != nil {
+			return Event{}, err
+		}
+
+		if ie.Type == _EV_SYN {
+			switch {
+			case ie.Code == _SYN_DROPPED:
+				// the kernel buffer overflowed, the events up to the next SYN_REPORT are incomplete
+				e.dropped = true
+			case ie.Code == _SYN_REPORT && e.dropped:
+				e.dropped = false
+				if err := e.resync(eventTime(&ie)); err != nil {
+					return Event{}, err
+				}
+			}
+			continue
+		}
+
+		if e.dropped {
+			continue
+		}
+		if ev, ok := e.translate(&ie); ok {
+			return ev, nil
+		}
+	}
+}
+
+// next returns the next input_event read from the device
+func (e *evdevSource) next() (inputEvent, error) {
+	var ie inputEvent
+
+	b, err := e.batch.next()
+	if err != nil {
+		return ie, err
+	}
+	copy((*[_INPUT_EVENT_SIZE]byte)(unsafe.Pointer(&ie))[:], b)
+	return ie, nil
+}
+
+// resync reads the state of every axis and button from the device after the kernel dropped
+// events, and queues an event for each one that changed since it was last reported
+func (e *evdevSource) resync(time uint32) error {
+	var keyState [(_KEY_MAX + 8) / 8]byte
+
+	if ioerr := e.dev.ioctl(_EVIOCGKEY(len(keyState)), unsafe.Pointer(&keyState)); ioerr != 0 {
+		return fmt.Errorf("EVIOCGKEY: %w", ioerr)
+	}
+
+	for code, button := range e.btnIdx {
+		if button < 0 {
+			continue
+		}
+		pressed := testBit(keyState[:], code)
+		if pressed == e.buttons[button] {
+			continue
+		}
+		e.buttons[button] = pressed
+		value := 0
+		if pressed {
+			value = 1
+		}
+		e.pending = append(e.pending, Event{Type: EventButton, Number: button, Value: value, Time: time})
+	}
+
+	for code, axis := range e.axisIdx {
+		if axis < 0 {
+			continue
+		}
+		var info AbsInfo
+		if ioerr := e.dev.ioctl(_EVIOCGABS(code), unsafe.Pointer(&info)); ioerr != 0 {
+			return fmt.Errorf("EVIOCGABS: %w", ioerr)
+		}
+		limits := e.absInfo[axis]
+		value := ScaleAxis(int64(info.Value), int64(limits.Minimum), int64(limits.Maximum))
+		if value == e.axes[axis] {
+			continue
+		}
+		e.axes[axis] = value
+		e.pending = append(e.pending, Event{Type: EventAxis, Number: axis, Value: value, Time: time})
+	}
+	return nil
+}
+
+// translate converts an input_event to a joystick Event.
+// ok is false for events that do not change the state of an axis or button
+func (e *evdevSource) translate(ie *inputEvent) (ev Event, ok bool) {
+	ev.Time = eventTime(ie)
+
+	switch ie.Type {
+	case _EV_KEY:
+		if int(ie.Code) > _KEY_MAX || e.btnIdx[ie.Code] < 0 || ie.Value > 1 {
+			// ignore unknown buttons and autorepeat
+			return ev, false
+		}
+		ev.Type = EventButton
+		ev.Number = e.btnIdx[ie.Code]
+		ev.Value = int(ie.Value)
+		e.buttons[ev.Number] = ie.Value != 0
+		return ev, true
+
+	case _EV_ABS:
+		if int(ie.Code) > _ABS_MAX || e.axisIdx[ie.Code] < 0 {
+			return ev, false
+		}
+		ev.Type = EventAxis
+		ev.Number = e.axisIdx[ie.Code]
+		info := e.absInfo[ev.Number]
+		ev.Value = ScaleAxis(int64(ie.Value), int64(info.Minimum), int64(info.Maximum))
+		e.axes[ev.Number] = ev.Value
+		return ev, true
+	}
+	return ev, false
+}
+
+// eventTime returns the timestamp of an input_event in milliseconds
+func eventTime(ie *inputEvent) uint32 {
+	return uint32(int64(ie.Time.Sec)*1000 + int64(ie.Time.Usec)/1000)
+}
+
+func testBit(bits []byte, bit int) bool {
+	return bits[bit/8]&(1<<uint(bit%8)) != 0
+}
@@ -0,0 +1,195 @@
+// +build linux
+
+package joystick
+
+import (
+	"os"
+	"reflect"
+	"syscall"
+	"testing"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// fakeEvdev is an evdev device node whose events are read from a pipe
+type fakeEvdev struct {
+	*os.File
+	keyState [(_KEY_MAX + 8) / 8]byte
+	abs      map[int]AbsInfo
+}
+
+func (d *fakeEvdev) ioctl(req int, ptr unsafe.Pointer) syscall.Errno {
+	if req == _EVIOCGKEY(len(d.keyState)) {
+		*(*[len(d.keyState)]byte)(ptr) = d.keyState
+		return 0
+	}
+	for code, info := range d.abs {
+		if req == _EVIOCGABS(code) {
+			*(*AbsInfo)(ptr) = info
+			return 0
+		}
+	}
+	return syscall.ENOTTY
+}
+
+func setBit(bits []byte, bit int) {
+	bits[bit/8] |= 1 << uint(bit%8)
+}
+
+// newFakeEvdev returns a source with an ABS_X axis ranging from 0 to 255,
+// and the buttons BTN_TRIGGER and BTN_THUMB, reading the events written to w
+func newFakeEvdev(t *testing.T) (src *evdevSource, dev *fakeEvdev, w *os.File) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		r.Close()
+		w.Close()
+	})
+
+	dev = &fakeEvdev{File: r, abs: map[int]AbsInfo{
+		int(AxisX): {Value: 128, Minimum: 0, Maximum: 255},
+	}}
+	var absBits [(_ABS_MAX + 8) / 8]byte
+	var keyBits [(_KEY_MAX + 8) / 8]byte
+	setBit(absBits[:], int(AxisX))
+	setBit(keyBits[:], int(ButtonTrigger))
+	setBit(keyBits[:], int(ButtonThumb))
+
+	src, err = newEvdevSource(dev, absBits[:], keyBits[:], dev.keyState[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return src, dev, w
+}
+
+func encodeInputEvents(events ...inputEvent) []byte {
+	b := []byte{}
+	for i := range events {
+		b = append(b, (*[_INPUT_EVENT_SIZE]byte)(unsafe.Pointer(&events[i]))[:]...)
+	}
+	return b
+}
+
+func inputEventAt(ms int64, typ, code uint16, value int32) inputEvent {
+	return inputEvent{Time: unix.NsecToTimeval(ms * 1e6), Type: typ, Code: code, Value: value}
+}
+
+func readEvents(t *testing.T, src *evdevSource, n int) []Event {
+	events := []Event{}
+	for i := 0; i < n; i++ {
+		ev, err := src.readEvent()
+		if err != nil {
+			t.Fatalf("event %d: %v", i, err)
+		}
+		events = append(events, ev)
+	}
+	return events
+}
+
+func TestEvdevSourcePartialReads(t *testing.T) {
+	src, _, w := newFakeEvdev(t)
+
+	data := encodeInputEvents(
+		inputEventAt(10, _EV_KEY, uint16(ButtonThumb), 1),
+		inputEventAt(10, _EV_SYN, _SYN_REPORT, 0),
+		inputEventAt(20, _EV_ABS, uint16(AxisX), 255),
+		inputEventAt(20, _EV_KEY, uint16(ButtonThumb), 2),
+		inputEventAt(20, _EV_SYN, _SYN_REPORT, 0),
+		inputEventAt(30, _EV_ABS, uint16(AxisX), 0),
+		inputEventAt(30, _EV_KEY, uint16(ButtonThumb), 0),
+	)
+
+	// write a few bytes at a time, so events are split across reads
+	go func() {
+		for i := 0; i < len(data); i += 5 {
+			end := i + 5
+			if end > len(data) {
+				end = len(data)
+			}
+			if _, err := w.Write(data[i:end]); err != nil {
+				return
+			}
+		}
+		w.Close()
+	}()
+
+	want := []Event{
+		{Type: EventButton, Number: 1, Value: 1, Time: 10},
+		{Type: EventAxis, Number: 0, Value: MaxAxisValue, Time: 20},
+		{Type: EventAxis, Number: 0, Value: MinAxisValue, Time: 30},
+		{Type: EventButton, Number: 1, Value: 0, Time: 30},
+	}
+	if got := readEvents(t, src, len(want)); !reflect.DeepEqual(got, want) {
+		t.Errorf("got events %v, want %v", got, want)
+	}
+	if _, err := src.readEvent(); err == nil {
+		t.Error("expected an error once the pipe is closed")
+	}
+}
+
+func TestEvdevSourceSynDropped(t *testing.T) {
+	src, dev, w := newFakeEvdev(t)
+
+	// while events were dropped the trigger was pressed and the axis moved
+	setBit(dev.keyState[:], int(ButtonTrigger))
+	dev.abs[int(AxisX)] = AbsInfo{Value: 255, Minimum: 0, Maximum: 255}
+
+	data := encodeInputEvents(
+		inputEventAt(10, _EV_KEY, uint16(ButtonThumb), 1),
+		inputEventAt(10, _EV_SYN, _SYN_REPORT, 0),
+		inputEventAt(20, _EV_SYN, _SYN_DROPPED, 0),
+		inputEventAt(20, _EV_ABS, uint16(AxisX), 10),
+		inputEventAt(20, _EV_SYN, _SYN_REPORT, 0),
+		inputEventAt(30, _EV_ABS, uint16(AxisX), 0),
+	)
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Event{
+		{Type: EventButton, Number: 1, Value: 1, Time: 10},
+		// the incomplete report is discarded and the state read back from the device:
+		// the thumb button is no longer pressed in the key state
+		{Type: EventButton, Number: 0, Value: 1, Time: 20},
+		{Type: EventButton, Number: 1, Value: 0, Time: 20},
+		{Type: EventAxis, Number: 0, Value: MaxAxisValue, Time: 20},
+		{Type: EventAxis, Number: 0, Value: MinAxisValue, Time: 30},
+	}
+	if got := readEvents(t, src, len(want)); !reflect.DeepEqual(got, want) {
+		t.Errorf("got events %v, want %v", got, want)
+	}
+}
+
+func TestEvdevSourceNumbering(t *testing.T) {
+	var absBits [(_ABS_MAX + 8) / 8]byte
+	var keyBits [(_KEY_MAX + 8) / 8]byte
+	var keyState [(_KEY_MAX + 8) / 8]byte
+	setBit(absBits[:], int(AxisY))
+	setBit(absBits[:], int(AxisX))
+	// BTN_MISC range buttons come after the BTN_JOYSTICK range ones, like joydev
+	setBit(keyBits[:], _BTN_MISC)
+	setBit(keyBits[:], int(ButtonTrigger))
+	setBit(keyState[:], _BTN_MISC)
+
+	dev := &fakeEvdev{abs: map[int]AbsInfo{
+		int(AxisX): {Value: -100, Minimum: -100, Maximum: 100},
+		int(AxisY): {Value: 0, Minimum: -100, Maximum: 100},
+	}}
+	src, err := newEvdevSource(dev, absBits[:], keyBits[:], keyState[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []int{MinAxisValue, 0}; !reflect.DeepEqual(src.axes, want) {
+		t.Errorf("got axes %v, want %v", src.axes, want)
+	}
+	if want := []bool{false, true}; !reflect.DeepEqual(src.buttons, want) {
+		t.Errorf("got buttons %v, want %v", src.buttons, want)
+	}
+	if src.btnIdx[ButtonTrigger] != 0 || src.btnIdx[_BTN_MISC] != 1 {
+		t.Errorf("got button numbers %d and %d, want 0 and 1", src.btnIdx[ButtonTrigger], src.btnIdx[_BTN_MISC])
+	}
+}
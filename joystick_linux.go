@@ -4,11 +4,17 @@ package joystick
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strconv"
 	"sync"
+	"syscall"
+	"time"
 	"unsafe"
 )
 
@@ -16,24 +22,50 @@ const (
 	_JS_EVENT_BUTTON uint8 = 0x01 /* button pressed/released */
 	_JS_EVENT_AXIS   uint8 = 0x02 /* joystick moved */
 	_JS_EVENT_INIT   uint8 = 0x80
+
+	_JS_EVENT_SIZE  = 8  /* size of struct js_event */
+	_JS_EVENT_BATCH = 64 /* number of events read at once */
 )
 
 var (
+	_JSIOCGVERSION = _IOR('j', 0x01, 4)  /* get driver version */
 	_JSIOCGAXES    = _IOR('j', 0x11, 1)  /* get number of axes */
 	_JSIOCGBUTTONS = _IOR('j', 0x12, 1)  /* get number of buttons */
 	_JSIOCGNAME    = func(len int) int { /* get identifier string */
 		return _IOR('j', 0x13, len)
 	}
+	_JSIOCSCORR   = _IOW('j', 0x21, int(unsafe.Sizeof(Correction{}))) /* set correction values */
+	_JSIOCGCORR   = _IOR('j', 0x22, int(unsafe.Sizeof(Correction{}))) /* get correction values */
+	_JSIOCGAXMAP  = _IOR('j', 0x32, _ABS_MAX+1)                       /* get axis mapping */
+	_JSIOCGBTNMAP = _IOR('j', 0x34, 2*(_KEY_MAX-_BTN_MISC+1))         /* get button mapping */
 )
 
+// eventSource decodes the stream of events read from a device
+type eventSource interface {
+	readEvent() (Event, error)
+}
+
 type joystickImpl struct {
+	// the device node, nil for a joystick returned by OpenReader
 	file        *os.File
+	closer      io.Closer
+	source      eventSource
 	axisCount   int
 	buttonCount int
 	name        string
 	state       State
 	mutex       sync.RWMutex
 	readerr     error
+	events      eventHub
+	axisCodes   []AxisCode
+	buttonCodes []ButtonCode
+	closed      bool
+	closeOnce   sync.Once
+	closeErr    error
+	done        chan struct{}
+	changes     changeNotifier
+	ready       bool
+	initCount   int
 }
 
 // Open opens the Joystick for reading, with the supplied id
@@ -45,69 +77,223 @@ type joystickImpl struct {
 // If successful, a Joystick interface is returned which can be used to
 // read the state of the joystick, else an error is returned
 func Open(id int) (Joystick, error) {
-	f, err := os.OpenFile(fmt.Sprintf("/dev/input/js%d", id), os.O_RDONLY, 0666)
+	return OpenPath(filepath.Join(devInputRoot, fmt.Sprintf("js%d", id)))
+}
+
+// OpenPath opens the joystick device node at path, for example "/dev/input/js0" or
+// a link under "/dev/input/by-id". Both joydev and evdev device nodes are accepted;
+// joysticks opened through evdev implement EvdevJoystick. evdev nodes without any
+// axis or joystick button, such as keyboards, are rejected with ErrNotJoystick, as are
+// files that are not input device nodes, such as regular files and FIFOs
+func OpenPath(path string) (Joystick, error) {
+	f, err := os.OpenFile(path, os.O_RDONLY, 0666)
 
 	if err != nil {
+		return nil, newOpenError(path, "open", err)
+	}
+
+	info, err := queryJoydev(f)
+	if errors.Is(err, ErrNotJoystick) {
+		// not a joydev node, it may be an evdev one
+		ejs, everr := newEvdevJoystick(f)
+		if everr == nil {
+			ejs.start()
+			return ejs, nil
+		}
+		var oe *OpenError
+		if errors.As(everr, &oe) && oe.Err == ErrNotJoystick {
+			// an evdev node without joystick inputs: report that rather than the failed joydev ioctl
+			err = everr
+		}
+	}
+	if err != nil {
+		f.Close()
 		return nil, err
 	}
 
+	js := newJoydevJoystick(f, info)
+	js.file = f
+	js.start()
+
+	return js, nil
+}
+
+// OpenReader returns a Joystick that reads joydev events, the js_event structs read from
+// /dev/input/jsN, from r. It lets programs be tested against recorded fixture files and FIFOs.
+//
+// The joystick has the name and the axis and button counts of info, and like a joydev device
+// it is ready once it has read an init event for each of them. It does not support calibration
+// or force feedback. The end of r, or a read error, is reported as a disconnection;
+// Close closes r and waits for the pending read to return
+func OpenReader(r io.ReadCloser, info DeviceInfo) (Joystick, error) {
+	if info.AxisCount < 0 || info.ButtonCount < 0 {
+		return nil, &OpenError{Op: "open", Err: errors.New("negative axis or button count")}
+	}
+
+	js := newJoydevJoystick(r, joydevInfo{axisCount: info.AxisCount, buttonCount: info.ButtonCount, name: info.Name})
+	js.start()
+
+	return js, nil
+}
+
+// newJoydevJoystick returns a joystick that reads joydev events from r
+func newJoydevJoystick(r io.ReadCloser, info joydevInfo) *joystickImpl {
+	js := &joystickImpl{}
+	js.axisCount = info.axisCount
+	js.buttonCount = info.buttonCount
+	js.closer = r
+	js.source = newJoydevSource(r)
+	js.name = info.name
+	js.axisCodes = info.axisCodes
+	js.buttonCodes = info.buttonCodes
+	js.state.AxisData = make([]int, info.axisCount, info.axisCount)
+	js.state.ButtonData = make([]bool, info.buttonCount, info.buttonCount)
+	js.ready = info.axisCount+info.buttonCount == 0
+	return js
+}
+
+// joydevInfo holds the properties of a joydev device
+type joydevInfo struct {
+	axisCount   int
+	buttonCount int
+	name        string
+	version     uint32
+	axisCodes   []AxisCode
+	buttonCodes []ButtonCode
+}
+
+// queryJoydev reads the properties of the joydev device f
+func queryJoydev(f *os.File) (joydevInfo, error) {
 	var axisCount uint8 = 0
 	var buttCount uint8 = 0
+	var version uint32 = 0
 	var buffer [256]byte
+	var axisMap [_ABS_MAX + 1]uint8
+	var buttonMap [_KEY_MAX - _BTN_MISC + 1]uint16
+
+	ioerr := ioctl(f, _JSIOCGVERSION, unsafe.Pointer(&version))
+	if ioerr != 0 {
+		return joydevInfo{}, newOpenError(f.Name(), "JSIOCGVERSION", ioerr)
+	}
 
-	ioerr := ioctl(f, _JSIOCGAXES, unsafe.Pointer(&axisCount))
+	ioerr = ioctl(f, _JSIOCGAXES, unsafe.Pointer(&axisCount))
 	if ioerr != 0 {
-		panic(ioerr)
+		return joydevInfo{}, newOpenError(f.Name(), "JSIOCGAXES", ioerr)
 	}
 
 	ioerr = ioctl(f, _JSIOCGBUTTONS, unsafe.Pointer(&buttCount))
 	if ioerr != 0 {
-		panic(ioerr)
+		return joydevInfo{}, newOpenError(f.Name(), "JSIOCGBUTTONS", ioerr)
 	}
 
 	ioerr = ioctl(f, _JSIOCGNAME(len(buffer)-1), unsafe.Pointer(&buffer))
 	if ioerr != 0 {
-		panic(ioerr)
+		return joydevInfo{}, newOpenError(f.Name(), "JSIOCGNAME", ioerr)
 	}
 
-	js := &joystickImpl{}
-	js.axisCount = int(axisCount)
-	js.buttonCount = int(buttCount)
-	js.file = f
-	js.name = string(buffer[:])
-	js.state.AxisData = make([]int, axisCount, axisCount)
+	ioerr = ioctl(f, _JSIOCGAXMAP, unsafe.Pointer(&axisMap))
+	if ioerr != 0 {
+		return joydevInfo{}, newOpenError(f.Name(), "JSIOCGAXMAP", ioerr)
+	}
 
-	go updateState(js)
+	ioerr = ioctl(f, _JSIOCGBTNMAP, unsafe.Pointer(&buttonMap))
+	if ioerr != 0 {
+		return joydevInfo{}, newOpenError(f.Name(), "JSIOCGBTNMAP", ioerr)
+	}
 
-	return js, nil
+	info := joydevInfo{
+		axisCount:   int(axisCount),
+		buttonCount: int(buttCount),
+		name:        string(bytes.TrimRight(buffer[:], "\x00")),
+		version:     version,
+	}
+	for i := 0; i < info.axisCount && i < len(axisMap); i++ {
+		info.axisCodes = append(info.axisCodes, AxisCode(axisMap[i]))
+	}
+	for i := 0; i < info.buttonCount && i < len(buttonMap); i++ {
+		info.buttonCodes = append(info.buttonCodes, ButtonCode(buttonMap[i]))
+	}
+	return info, nil
+}
+
+// newOpenError returns an OpenError that matches the sentinel error corresponding to err
+func newOpenError(path, op string, err error) *OpenError {
+	e := &OpenError{Path: path, Op: op, Err: err}
+
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		switch errno {
+		case syscall.ENOENT, syscall.ENODEV, syscall.ENXIO:
+			e.kind = ErrNotFound
+		case syscall.EACCES, syscall.EPERM:
+			e.kind = ErrPermission
+		case syscall.ENOTTY, syscall.EINVAL:
+			e.kind = ErrNotJoystick
+		}
+	}
+	return e
+}
+
+// start launches the goroutine that reads the events of the joystick
+func (js *joystickImpl) start() {
+	js.done = make(chan struct{})
+	go updateState(js)
 }
 
 func updateState(js *joystickImpl) {
 	var err error
-	var ev event
+	var ev Event
+
+	defer close(js.done)
 
 	for err == nil {
-		ev, err = js.getEvent()
-
-		if ev.Type&_JS_EVENT_BUTTON != 0 {
-			js.mutex.Lock()
-			if ev.Value == 0 {
-				js.state.Buttons &= ^(1 << uint(ev.Number))
-			} else {
-				js.state.Buttons |= 1 << ev.Number
-			}
-			js.mutex.Unlock()
+		ev, err = js.source.readEvent()
+		if err != nil {
+			break
 		}
 
-		if ev.Type&_JS_EVENT_AXIS != 0 {
-			js.mutex.Lock()
-			js.state.AxisData[ev.Number] = int(ev.Value)
-			js.mutex.Unlock()
-		}
+		js.mutex.Lock()
+		js.apply(ev)
+		js.mutex.Unlock()
+
+		js.events.publish(ev)
 	}
 	js.mutex.Lock()
-	js.readerr = err
+	js.readerr = fmt.Errorf("%w: %v", ErrDisconnected, err)
+	js.changes.notify()
 	js.mutex.Unlock()
+	js.events.close()
+}
+
+// apply updates the joystick state with ev. Must be called with the mutex held
+func (js *joystickImpl) apply(ev Event) {
+	changed := false
+	switch ev.Type {
+	case EventButton:
+		if ev.Init {
+			// the state of the buttons when the device was opened, not a press or release
+			changed = js.state.setButton(ev.Number, ev.Value != 0)
+		} else {
+			changed = js.state.updateButton(ev.Number, ev.Value != 0)
+		}
+	case EventAxis:
+		changed = js.state.setAxis(ev.Number, ev.Value)
+	}
+
+	// the driver sends an init event for every axis and button after open,
+	// any other event means it has finished
+	if !js.ready {
+		js.initCount++
+		if !ev.Init || js.initCount >= js.axisCount+js.buttonCount {
+			js.ready = true
+			js.changes.notify()
+		}
+	}
+
+	if changed {
+		js.state.markChanged(time.Now())
+		js.changes.notify()
+	}
 }
 
 func (js *joystickImpl) AxisCount() int {
@@ -122,15 +308,146 @@ func (js *joystickImpl) Name() string {
 	return js.name
 }
 
+func (js *joystickImpl) AxisCodes() []AxisCode {
+	return append([]AxisCode(nil), js.axisCodes...)
+}
+
+func (js *joystickImpl) ButtonCodes() []ButtonCode {
+	return append([]ButtonCode(nil), js.buttonCodes...)
+}
+
+func (js *joystickImpl) Correction() ([]Correction, error) {
+	if err := js.checkCalibration(); err != nil {
+		return nil, err
+	}
+	corr := make([]Correction, js.axisCount)
+	if ioerr := ioctl(js.file, _JSIOCGCORR, unsafe.Pointer(&corr[0])); ioerr != 0 {
+		return nil, fmt.Errorf("JSIOCGCORR: %w", ioerr)
+	}
+	return corr, nil
+}
+
+func (js *joystickImpl) SetCorrection(corr []Correction) error {
+	if err := js.checkCalibration(); err != nil {
+		return err
+	}
+	if len(corr) != js.axisCount {
+		return fmt.Errorf("expected correction for %d axes, got %d", js.axisCount, len(corr))
+	}
+	if ioerr := ioctl(js.file, _JSIOCSCORR, unsafe.Pointer(&corr[0])); ioerr != 0 {
+		return fmt.Errorf("JSIOCSCORR: %w", ioerr)
+	}
+	return nil
+}
+
+func (js *joystickImpl) ResetCorrection() error {
+	return js.SetCorrection(make([]Correction, js.axisCount))
+}
+
+// checkCalibration returns an error if the correction ioctls cannot be used on this joystick
+func (js *joystickImpl) checkCalibration() error {
+	if _, ok := js.source.(*joydevSource); !ok || js.file == nil {
+		return errors.New("calibration is only supported by joydev devices")
+	}
+	if js.axisCount == 0 {
+		return errors.New("joystick has no axis to calibrate")
+	}
+	return nil
+}
+
 func (js *joystickImpl) Read() (State, error) {
-	js.mutex.RLock()
-	state, err := js.state, js.readerr
-	js.mutex.RUnlock()
+	var state State
+	err := js.ReadInto(&state)
+	return state, err
+}
+
+func (js *joystickImpl) ReadInto(state *State) error {
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+
+	return js.snapshot(state)
+}
+
+// WaitForChange blocks until the reader goroutine applies a change newer than since
+func (js *joystickImpl) WaitForChange(ctx context.Context, since uint64) (State, error) {
+	var state State
+	err := js.changes.waitFor(ctx, &js.mutex, func() bool {
+		return js.state.Sequence > since || js.readerr != nil || js.closed
+	})
+	if err != nil {
+		return state, err
+	}
+	defer js.mutex.Unlock()
+
+	err = js.snapshot(&state)
 	return state, err
 }
 
-func (js *joystickImpl) Close() {
-	js.file.Close()
+// Ready returns true once the driver has reported the initial state of every axis and button
+func (js *joystickImpl) Ready() bool {
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+	return js.ready
+}
+
+func (js *joystickImpl) WaitReady(ctx context.Context) error {
+	err := js.changes.waitFor(ctx, &js.mutex, func() bool {
+		return js.ready || js.readerr != nil || js.closed
+	})
+	if err != nil {
+		return err
+	}
+	defer js.mutex.Unlock()
+
+	if js.ready {
+		return nil
+	}
+	if js.closed {
+		return ErrClosed
+	}
+	return js.readerr
+}
+
+// snapshot implements ReadInto. Must be called with the mutex held
+func (js *joystickImpl) snapshot(state *State) error {
+	js.state.readInto(state)
+	if js.closed {
+		return ErrClosed
+	}
+	return js.readerr
+}
+
+// peekState implements statePeeker
+func (js *joystickImpl) peekState(state *State) error {
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+
+	js.state.copyInto(state)
+	if js.closed {
+		return ErrClosed
+	}
+	return js.readerr
+}
+
+func (js *joystickImpl) Events(bufferSize int, policy OverflowPolicy) <-chan Event {
+	return js.events.subscribe(bufferSize, policy)
+}
+
+// Close closes the device, which stops the reader goroutine, and waits for it to exit
+func (js *joystickImpl) Close() error {
+	js.closeOnce.Do(func() {
+		js.mutex.Lock()
+		js.closed = true
+		js.changes.notify()
+		js.mutex.Unlock()
+
+		// the device is read through the poller, the ioctls leaving it non-blocking,
+		// so closing it wakes up a pending read
+		js.closeErr = js.closer.Close()
+		js.events.interrupt()
+		<-js.done
+	})
+	return js.closeErr
 }
 
 type event struct {
@@ -140,6 +457,21 @@ type event struct {
 	Number uint8  /* axis/button number */
 }
 
+func (j *event) toEvent() Event {
+	ev := Event{
+		Number: int(j.Number),
+		Value:  int(j.Value),
+		Time:   j.Time,
+		Init:   j.Type&_JS_EVENT_INIT != 0,
+	}
+	if j.Type&_JS_EVENT_AXIS != 0 {
+		ev.Type = EventAxis
+	} else {
+		ev.Type = EventButton
+	}
+	return ev
+}
+
 func (j *event) String() string {
 	var Type, Number string
 
@@ -158,23 +490,64 @@ func (j *event) String() string {
 	return fmt.Sprintf("[Time: %v, Type: %v, Number: %v, Value: %v]", j.Time, Type, Number, j.Value)
 }
 
-func (j *joystickImpl) getEvent() (event, error) {
-	var ev event
+// batchReader reads fixed size records many at a time, and returns them one by one
+type batchReader struct {
+	r    io.Reader
+	size int
+	buf  []byte
+	pos  int
+	end  int
+}
+
+// newBatchReader returns a batchReader reading records of size bytes from r, up to batch at once
+func newBatchReader(r io.Reader, size, batch int) batchReader {
+	return batchReader{r: r, size: size, buf: make([]byte, size*batch)}
+}
+
+// next returns the next record, reading a batch of records when none is left in buf.
+// The record is only valid until the following call
+func (b *batchReader) next() ([]byte, error) {
+	for b.end-b.pos < b.size {
+		// keep the start of a partial record, which only happens when reading from a pipe
+		b.end = copy(b.buf, b.buf[b.pos:b.end])
+		b.pos = 0
 
-	if j.file == nil {
-		panic("file is nil")
+		n, err := b.r.Read(b.buf[b.end:])
+		if err != nil {
+			return nil, err
+		}
+		b.end += n
 	}
 
-	b := make([]byte, 8)
-	_, err := j.file.Read(b)
+	rec := b.buf[b.pos : b.pos+b.size]
+	b.pos += b.size
+	return rec, nil
+}
+
+// joydevSource reads js_event structs from a /dev/input/jsN device
+type joydevSource struct {
+	batch batchReader
+}
+
+func newJoydevSource(r io.Reader) *joydevSource {
+	return &joydevSource{newBatchReader(r, _JS_EVENT_SIZE, _JS_EVENT_BATCH)}
+}
+
+func (j *joydevSource) readEvent() (Event, error) {
+	b, err := j.batch.next()
 	if err != nil {
-		return event{}, err
+		return Event{}, err
 	}
+	ev := decodeEvent(b)
+	return ev.toEvent(), nil
+}
 
-	data := bytes.NewReader(b)
-	err = binary.Read(data, binary.LittleEndian, &ev)
-	if err != nil {
-		return event{}, err
+// decodeEvent decodes a little endian js_event
+func decodeEvent(b []byte) event {
+	return event{
+		Time:   binary.LittleEndian.Uint32(b[0:4]),
+		Value:  int16(binary.LittleEndian.Uint16(b[4:6])),
+		Type:   b[6],
+		Number: b[7],
 	}
-	return ev, nil
 }
@@ -0,0 +1,541 @@
+// +build linux
+
+package joystick
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// encodeJsEvents returns the events laid out as the js_event structs read from a joydev node
+func encodeJsEvents(events ...event) []byte {
+	b := make([]byte, 0, len(events)*_JS_EVENT_SIZE)
+	for _, ev := range events {
+		b = binary.LittleEndian.AppendUint32(b, ev.Time)
+		b = binary.LittleEndian.AppendUint16(b, uint16(ev.Value))
+		b = append(b, ev.Type, ev.Number)
+	}
+	return b
+}
+
+// newPipeJoystick returns a started joydev joystick reading the events written to w
+func newPipeJoystick(t *testing.T, info joydevInfo) (js *joystickImpl, w *os.File) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		w.Close()
+	})
+
+	js = newJoydevJoystick(r, info)
+	js.start()
+	t.Cleanup(func() {
+		js.Close()
+	})
+	return js, w
+}
+
+func TestJoydevJoystick(t *testing.T) {
+	info := joydevInfo{
+		axisCount:   2,
+		buttonCount: 2,
+		name:        "pipe",
+		axisCodes:   []AxisCode{AxisX, AxisY},
+		buttonCodes: []ButtonCode{ButtonTrigger, ButtonThumb},
+	}
+	js, w := newPipeJoystick(t, info)
+	if js.Name() != "pipe" || js.AxisCount() != 2 || js.ButtonCount() != 2 || FindButton(js, ButtonThumb) != 1 {
+		t.Errorf("got name %q, %d axis, %d buttons, codes %v %v", js.Name(), js.AxisCount(), js.ButtonCount(), js.AxisCodes(), js.ButtonCodes())
+	}
+	if js.Ready() {
+		t.Error("ready before the init events")
+	}
+
+	events := js.Events(16, Block)
+	sent := []event{
+		{Time: 1, Value: 100, Type: _JS_EVENT_AXIS | _JS_EVENT_INIT, Number: 0},
+		{Time: 1, Value: -100, Type: _JS_EVENT_AXIS | _JS_EVENT_INIT, Number: 1},
+		{Time: 1, Value: 0, Type: _JS_EVENT_BUTTON | _JS_EVENT_INIT, Number: 0},
+		{Time: 1, Value: 1, Type: _JS_EVENT_BUTTON | _JS_EVENT_INIT, Number: 1},
+		{Time: 20, Value: 32767, Type: _JS_EVENT_AXIS, Number: 0},
+		{Time: 30, Value: 1, Type: _JS_EVENT_BUTTON, Number: 0},
+	}
+	b := encodeJsEvents(sent...)
+	// written in two parts, splitting an event
+	for _, part := range [][]byte{b[:13], b[13:]} {
+		if _, err := w.Write(part); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, want := range sent {
+		select {
+		case got := <-events:
+			if got != want.toEvent() {
+				t.Errorf("got event %+v, want %+v", got, want.toEvent())
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for events")
+		}
+	}
+
+	state, err := js.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !js.Ready() {
+		t.Error("not ready after the init events")
+	}
+	if !reflect.DeepEqual(state.AxisData, []int{32767, -100}) || !reflect.DeepEqual(state.ButtonData, []bool{true, true}) {
+		t.Errorf("got axis %v and buttons %v", state.AxisData, state.ButtonData)
+	}
+	// init events are not presses
+	if !state.JustPressed(0) || state.JustPressed(1) {
+		t.Errorf("got presses %v, want button 0 only", state.Presses)
+	}
+
+	// the writer going away is a disconnection
+	w.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := WaitForChange(ctx, js, state.Sequence); !errors.Is(err, ErrDisconnected) {
+		t.Errorf("got %v after the writer closed, want ErrDisconnected", err)
+	}
+}
+
+func TestOpenPathNotJoystick(t *testing.T) {
+	dir := t.TempDir()
+
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, make([]byte, 64), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// opening a FIFO for reading blocks until it has a writer
+	fifo := filepath.Join(dir, "fifo")
+	if err := syscall.Mkfifo(fifo, 0644); err != nil {
+		t.Fatal(err)
+	}
+	writer := make(chan *os.File, 1)
+	go func() {
+		w, _ := os.OpenFile(fifo, os.O_WRONLY, 0)
+		writer <- w
+	}()
+	defer func() {
+		if w := <-writer; w != nil {
+			w.Close()
+		}
+	}()
+
+	for _, path := range []string{file, fifo, os.DevNull} {
+		js, err := OpenPath(path)
+		if err == nil {
+			js.Close()
+		}
+		if !errors.Is(err, ErrNotJoystick) {
+			t.Errorf("OpenPath(%s): got %v, want ErrNotJoystick", path, err)
+		}
+	}
+}
+
+func TestOpenNotFound(t *testing.T) {
+	dev, _ := fakeInputTree(t)
+
+	if _, err := OpenPath(filepath.Join(dev, "js0")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("OpenPath: got %v, want ErrNotFound", err)
+	}
+	if _, err := Open(3); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open: got %v, want ErrNotFound", err)
+	}
+	if _, err := OpenByName("pad"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("OpenByName: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestHasJoystickInputs(t *testing.T) {
+	tests := []struct {
+		abs, keys []int
+		want      bool
+	}{
+		{nil, nil, false},
+		// keyboard
+		{nil, []int{1, 30, 0xff}, false},
+		{[]int{int(AxisX)}, nil, true},
+		{[]int{_ABS_MAX}, nil, true},
+		{nil, []int{_BTN_MISC}, true},
+		{nil, []int{30, int(ButtonSouth)}, true},
+		{nil, []int{_KEY_MAX}, true},
+	}
+	for _, test := range tests {
+		var absBits [(_ABS_MAX + 8) / 8]byte
+		var keyBits [(_KEY_MAX + 8) / 8]byte
+		for _, code := range test.abs {
+			setBit(absBits[:], code)
+		}
+		for _, code := range test.keys {
+			setBit(keyBits[:], code)
+		}
+		if got := hasJoystickInputs(absBits[:], keyBits[:]); got != test.want {
+			t.Errorf("axis %v, keys %v: got %v, want %v", test.abs, test.keys, got, test.want)
+		}
+	}
+}
+
+// TestJoydevConcurrency reads a joystick from many goroutines while its reader goroutine
+// applies events, then closes it. Run with -race
+func TestJoydevConcurrency(t *testing.T) {
+	js, w := newPipeJoystick(t, joydevInfo{axisCount: 4, buttonCount: 8})
+
+	var wg sync.WaitGroup
+	readers := []func() error{
+		func() error {
+			_, err := js.Read()
+			return err
+		},
+		func() error {
+			var state State
+			return js.ReadInto(&state)
+		},
+		func() error {
+			var state State
+			return ReadInto(js, &state)
+		},
+		func() error {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+			defer cancel()
+			_, err := WaitForChange(ctx, js, 0)
+			if errors.Is(err, context.DeadlineExceeded) {
+				return nil
+			}
+			return err
+		},
+	}
+	for _, read := range readers {
+		wg.Add(1)
+		go func(read func() error) {
+			defer wg.Done()
+			for {
+				if err := read(); err != nil {
+					if !errors.Is(err, ErrClosed) {
+						t.Errorf("got %v, want ErrClosed", err)
+					}
+					return
+				}
+				// let the reader goroutine run, even on a single CPU
+				runtime.Gosched()
+			}
+		}(read)
+	}
+	for _, policy := range []OverflowPolicy{Block, DropOldest, DropNewest} {
+		wg.Add(1)
+		go func(events <-chan Event) {
+			defer wg.Done()
+			for range events {
+			}
+		}(js.Events(4, policy))
+	}
+
+	const count = 2000
+	var sent []event
+	for i := 0; i < count; i++ {
+		sent = append(sent,
+			event{Time: uint32(i), Value: int16(i), Type: _JS_EVENT_AXIS, Number: uint8(i % 4)},
+			event{Time: uint32(i), Value: int16(i % 2), Type: _JS_EVENT_BUTTON, Number: uint8(i % 8)})
+	}
+	if _, err := w.Write(encodeJsEvents(sent...)); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	state, err := WaitFor(ctx, js, func(s State) bool {
+		return s.AxisData[(count-1)%4] == count-1
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{count - 4, count - 3, count - 2, count - 1}; !reflect.DeepEqual(state.AxisData, want) {
+		t.Errorf("got axis %v, want %v", state.AxisData, want)
+	}
+
+	if err := js.Close(); err != nil {
+		t.Fatal(err)
+	}
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("readers still running after Close")
+	}
+}
+
+// TestCloseAfterIoctl checks that the ioctls leave the device non-blocking, so that Close
+// does not hang on a device that sends nothing
+func TestCloseAfterIoctl(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if _, err := queryJoydev(r); !errors.Is(err, ErrNotJoystick) {
+		t.Errorf("queryJoydev on a pipe: got %v, want ErrNotJoystick", err)
+	}
+	if _, err := newEvdevJoystick(r); !errors.Is(err, ErrNotJoystick) {
+		t.Errorf("newEvdevJoystick on a pipe: got %v, want ErrNotJoystick", err)
+	}
+
+	js := newJoydevJoystick(r, joydevInfo{axisCount: 1})
+	events := js.Events(1, Block)
+	js.start()
+
+	// once the event is delivered the reader goroutine goes back to reading the idle pipe
+	if _, err := w.Write(encodeJsEvents(event{Value: 1, Type: _JS_EVENT_AXIS})); err != nil {
+		t.Fatal(err)
+	}
+	<-events
+	time.Sleep(10 * time.Millisecond)
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- js.Close()
+	}()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close blocked on the pending read")
+	}
+
+	if ioerr := ioctl(r, _JSIOCGVERSION, unsafe.Pointer(new(uint32))); ioerr != syscall.EBADF {
+		t.Errorf("ioctl after Close: got %v, want EBADF", ioerr)
+	}
+}
+
+// BenchmarkJoydevSource decodes events written to a pipe, 1000 per iteration
+func BenchmarkJoydevSource(b *testing.B) {
+	benchmarkJoydevRead(b, func(r io.Reader) func() (Event, error) {
+		return newJoydevSource(r).readEvent
+	})
+}
+
+// BenchmarkJoydevSourceUnbatched is the reference for BenchmarkJoydevSource: it decodes the
+// same events the way joydev events were read before batching, one read and binary.Read each
+func BenchmarkJoydevSourceUnbatched(b *testing.B) {
+	benchmarkJoydevRead(b, func(r io.Reader) func() (Event, error) {
+		return func() (Event, error) {
+			var ev event
+			buf := make([]byte, _JS_EVENT_SIZE)
+			if _, err := r.Read(buf); err != nil {
+				return Event{}, err
+			}
+			if err := binary.Read(bytes.NewReader(buf), binary.LittleEndian, &ev); err != nil {
+				return Event{}, err
+			}
+			return ev.toEvent(), nil
+		}
+	})
+}
+
+// benchmarkJoydevRead writes 1000 events to a pipe per iteration, and reads them back
+// with the function returned by newRead for the read end of the pipe
+func benchmarkJoydevRead(b *testing.B, newRead func(r io.Reader) func() (Event, error)) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	const count = 1000
+	var sent []event
+	for i := 0; i < count; i++ {
+		sent = append(sent, event{Time: uint32(i), Value: int16(i), Type: _JS_EVENT_AXIS, Number: uint8(i % 4)})
+	}
+	data := encodeJsEvents(sent...)
+	read := newRead(r)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := w.Write(data); err != nil {
+			b.Fatal(err)
+		}
+		for n := 0; n < count; n++ {
+			if _, err := read(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// TestCloseBlockedReader checks that Close releases a reader goroutine blocked on a full Block subscriber
+func TestCloseBlockedReader(t *testing.T) {
+	js, w := newPipeJoystick(t, joydevInfo{axisCount: 1})
+	events := js.Events(1, Block)
+
+	var sent []event
+	for i := 0; i < 8; i++ {
+		sent = append(sent, event{Value: int16(i + 1), Type: _JS_EVENT_AXIS})
+	}
+	if _, err := w.Write(encodeJsEvents(sent...)); err != nil {
+		t.Fatal(err)
+	}
+	// the first event is buffered, the reader goroutine then blocks on the second
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := WaitFor(ctx, js, func(s State) bool { return s.AxisData[0] == 2 }); err != nil {
+		t.Fatal(err)
+	}
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- js.Close()
+	}()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close blocked by the Block subscriber")
+	}
+	if got := drain(events); len(got) != 1 || got[0] != 1 {
+		t.Errorf("got %v, want [1]", got)
+	}
+}
+
+func TestOpenReader(t *testing.T) {
+	// a fixture file: the init burst, then a few changes
+	fixture := filepath.Join(t.TempDir(), "js0.events")
+	err := os.WriteFile(fixture, encodeJsEvents(
+		event{Type: _JS_EVENT_AXIS | _JS_EVENT_INIT, Number: 0, Value: 10},
+		event{Type: _JS_EVENT_AXIS | _JS_EVENT_INIT, Number: 1},
+		event{Type: _JS_EVENT_BUTTON | _JS_EVENT_INIT, Number: 0, Value: 1},
+		event{Time: 100, Type: _JS_EVENT_AXIS, Number: 1, Value: -300},
+		event{Time: 200, Type: _JS_EVENT_BUTTON, Number: 0},
+		// inputs the joystick does not have are ignored
+		event{Time: 300, Type: _JS_EVENT_AXIS, Number: 5, Value: 1},
+	), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	js, err := OpenReader(f, DeviceInfo{Name: "fixture", AxisCount: 2, ButtonCount: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer js.Close()
+	if js.Name() != "fixture" || js.AxisCount() != 2 || js.ButtonCount() != 1 {
+		t.Errorf("got name %q, %d axis, %d buttons", js.Name(), js.AxisCount(), js.ButtonCount())
+	}
+
+	// the end of the file is a disconnection, once every event is applied
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	state, err := WaitForChange(ctx, js, math.MaxUint64)
+	if !errors.Is(err, ErrDisconnected) {
+		t.Fatalf("got %v at the end of the file, want ErrDisconnected", err)
+	}
+	if !reflect.DeepEqual(state.AxisData, []int{10, -300}) || state.Pressed(0) || !state.JustReleased(0) {
+		t.Errorf("got axis %v, buttons %v", state.AxisData, state.ButtonData)
+	}
+
+	if _, err := js.(Calibrator).Correction(); err == nil {
+		t.Error("calibration of a joystick without device node")
+	}
+	if _, err := js.(ForceFeedbacker).ForceFeedback(); err == nil {
+		t.Error("force feedback on a joystick without device node")
+	}
+	if err := js.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := js.Read(); err != ErrClosed {
+		t.Errorf("got %v after Close, want ErrClosed", err)
+	}
+
+	if _, err := OpenReader(f, DeviceInfo{AxisCount: -1}); err == nil {
+		t.Error("joystick opened with a negative axis count")
+	}
+}
+
+func TestOpenReaderFIFO(t *testing.T) {
+	fifo := filepath.Join(t.TempDir(), "fifo")
+	if err := syscall.Mkfifo(fifo, 0644); err != nil {
+		t.Fatal(err)
+	}
+	// opening a FIFO blocks until it has both a reader and a writer
+	writer := make(chan *os.File, 1)
+	go func() {
+		w, _ := os.OpenFile(fifo, os.O_WRONLY, 0)
+		writer <- w
+	}()
+	r, err := os.Open(fifo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := <-writer
+	if w == nil {
+		t.Fatal("cannot open the FIFO for writing")
+	}
+	defer w.Close()
+
+	js, err := OpenReader(r, DeviceInfo{Name: "fifo", AxisCount: 1, ButtonCount: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer js.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// an event split across writes is decoded once complete
+	b := encodeJsEvents(
+		event{Type: _JS_EVENT_AXIS | _JS_EVENT_INIT},
+		event{Type: _JS_EVENT_BUTTON | _JS_EVENT_INIT},
+		event{Time: 10, Type: _JS_EVENT_AXIS, Value: 1234},
+	)
+	for _, part := range [][]byte{b[:20], b[20:]} {
+		if _, err := w.Write(part); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := WaitReady(ctx, js); err != nil {
+		t.Fatal(err)
+	}
+	state, err := WaitFor(ctx, js, func(s State) bool { return s.AxisData[0] == 1234 })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// closing the writer disconnects the joystick
+	w.Close()
+	if _, err := WaitForChange(ctx, js, state.Sequence); !errors.Is(err, ErrDisconnected) {
+		t.Errorf("got %v after the writer closed, want ErrDisconnected", err)
+	}
+}
@@ -5,7 +5,8 @@ package joystick
 import (
 	"fmt"
 	"golang.org/x/sys/windows"
-	"math"
+	"sync"
+	"time"
 	"unsafe"
 )
 
@@ -81,6 +82,7 @@ var (
 	winmmdll      = windows.MustLoadDLL("Winmm.dll")
 	joyGetPosEx   = winmmdll.MustFindProc("joyGetPosEx")
 	joyGetDevCaps = winmmdll.MustFindProc("joyGetDevCapsW")
+	joyGetNumDevs = winmmdll.MustFindProc("joyGetNumDevs")
 )
 
 type axisLimit struct {
@@ -89,16 +91,16 @@ type axisLimit struct {
 
 type joystickImpl struct {
 	id           int
+	caps         JOYCAPS
 	axisCount    int
 	povAxisCount int
 	buttonCount  int
 	name         string
-	state        State
 	axisLimits   []axisLimit
-}
-
-func mapValue(val, srcMin, srcMax, dstMin, dstMax int64) int64 {
-	return (val-srcMin)*(dstMax-dstMin)/(srcMax-srcMin) + dstMin
+	// guards the state, which is updated by each poll, and closed
+	mutex  sync.Mutex
+	state  State
+	closed bool
 }
 
 // Open opens the Joystick for reading, with the supplied id
@@ -117,17 +119,46 @@ func Open(id int) (Joystick, error) {
 
 	err := js.getJoyCaps()
 	if err == nil {
+		// buttons held when the joystick is opened were not pressed since the previous Read
+		js.getJoyPosEx()
+		js.state.clearEdges()
 		return js, nil
 	}
 	return nil, err
 }
 
+// Enumerate returns information on every joystick currently attached, sorted by id
+func Enumerate() ([]DeviceInfo, error) {
+	devices := []DeviceInfo{}
+
+	count, _, _ := joyGetNumDevs.Call()
+	for id := 0; id < int(count); id++ {
+		js := &joystickImpl{}
+		js.id = id
+
+		if js.getJoyCaps() != nil || js.getJoyPosEx() != nil {
+			continue
+		}
+
+		devices = append(devices, DeviceInfo{
+			ID:          id,
+			Name:        js.name,
+			Vendor:      js.caps.wMid,
+			Product:     js.caps.wPid,
+			AxisCount:   js.AxisCount(),
+			ButtonCount: js.buttonCount,
+		})
+	}
+
+	return devices, nil
+}
+
 func (js *joystickImpl) getJoyCaps() error {
-	var caps JOYCAPS
-	ret, _, _ := joyGetDevCaps.Call(uintptr(js.id), uintptr(unsafe.Pointer(&caps)), unsafe.Sizeof(caps))
+	caps := &js.caps
+	ret, _, _ := joyGetDevCaps.Call(uintptr(js.id), uintptr(unsafe.Pointer(caps)), unsafe.Sizeof(*caps))
 
 	if ret != 0 {
-		return fmt.Errorf("Failed to read Joystick %d", js.id)
+		return &OpenError{Op: "joyGetDevCaps", Err: ErrNotFound}
 	} else {
 		js.axisCount = int(caps.wNumAxes)
 		js.buttonCount = int(caps.wNumButtons)
@@ -138,6 +169,7 @@ func (js *joystickImpl) getJoyCaps() error {
 		}
 
 		js.state.AxisData = make([]int, js.axisCount+js.povAxisCount, js.axisCount+js.povAxisCount)
+		js.state.ButtonData = make([]bool, js.buttonCount, js.buttonCount)
 
 		js.axisLimits = []axisLimit{
 			{caps.wXmin, caps.wXmax},
@@ -152,17 +184,6 @@ func (js *joystickImpl) getJoyCaps() error {
 	}
 }
 
-func axisFromPov(povVal float64) int {
-	switch {
-	case povVal < -0.5:
-		return -32767
-	case povVal > 0.5:
-		return 32768
-	default:
-		return 0
-	}
-}
-
 func (js *joystickImpl) getJoyPosEx() error {
 	var info JOYINFOEX
 	info.dwSize = uint32(unsafe.Sizeof(info))
@@ -170,28 +191,26 @@ func (js *joystickImpl) getJoyPosEx() error {
 	ret, _, _ := joyGetPosEx.Call(uintptr(js.id), uintptr(unsafe.Pointer(&info)))
 
 	if ret != 0 {
-		return fmt.Errorf("Failed to read Joystick %d", js.id)
+		return fmt.Errorf("Failed to read Joystick %d: %w", js.id, ErrDisconnected)
 	} else {
-		js.state.Buttons = info.dwButtons
+		changed := false
+		for i := 0; i < 32; i++ {
+			changed = js.state.updateButton(i, info.dwButtons&(1<<uint(i)) != 0) || changed
+		}
 
 		for i := 0; i < js.axisCount; i++ {
-			js.state.AxisData[i] = int(mapValue(int64(info.dwAxis[i]),
-				int64(js.axisLimits[i].min), int64(js.axisLimits[i].max), -32767, 32768))
+			value := ScaleAxis(int64(info.dwAxis[i]), int64(js.axisLimits[i].min), int64(js.axisLimits[i].max))
+			changed = js.state.setAxis(i, value) || changed
 		}
 
 		if js.povAxisCount > 0 {
-			angleDeg := float64(info.dwPOV) / 100.0
-			if angleDeg > 359.0 {
-				js.state.AxisData[js.axisCount] = 0
-				js.state.AxisData[js.axisCount+1] = 0
-				return nil
-			}
-
-			angleRad := angleDeg * math.Pi / 180.0
-			sin, cos := math.Sincos(angleRad)
-
-			js.state.AxisData[js.axisCount] = axisFromPov(sin)
-			js.state.AxisData[js.axisCount+1] = axisFromPov(-cos)
+			povX, povY := povAxes(info.dwPOV)
+			changed = js.state.setAxis(js.axisCount, povX) || changed
+			changed = js.state.setAxis(js.axisCount+1, povY) || changed
+		}
+
+		if changed {
+			js.state.markChanged(time.Now())
 		}
 		return nil
 	}
@@ -210,10 +229,28 @@ func (js *joystickImpl) Name() string {
 }
 
 func (js *joystickImpl) Read() (State, error) {
+	var state State
+	err := js.ReadInto(&state)
+	return state, err
+}
+
+func (js *joystickImpl) ReadInto(state *State) error {
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+
+	if js.closed {
+		js.state.readInto(state)
+		return ErrClosed
+	}
 	err := js.getJoyPosEx()
-	return js.state, err
+	js.state.readInto(state)
+	return err
 }
 
-func (js *joystickImpl) Close() {
-	// no impl under windows
+func (js *joystickImpl) Close() error {
+	// the winmm joystick api has nothing to release
+	js.mutex.Lock()
+	js.closed = true
+	js.mutex.Unlock()
+	return nil
 }
@@ -17,3 +17,8 @@ import (
 func Open(id int) (Joystick, error) {
 	return nil, errors.New("Joystick API unsupported on this platform")
 }
+
+// Enumerate returns information on every joystick currently attached, sorted by id
+func Enumerate() ([]DeviceInfo, error) {
+	return nil, errors.New("Joystick API unsupported on this platform")
+}
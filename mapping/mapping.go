@@ -0,0 +1,348 @@
+// Package mapping reads controller mappings in the SDL_GameControllerDB format
+// and applies them to joysticks.
+//
+// Each line of gamecontrollerdb.txt describes one controller model:
+//   03000000de280000ff11000001000000,Steam Virtual Gamepad,a:b0,b:b1,leftx:a0,...,platform:Linux,
+//
+// Example:
+//   f, _ := os.Open("gamecontrollerdb.txt")
+//   db, err := mapping.ParseDB(f)
+//   if err != nil {
+//     panic(err)
+//   }
+//
+//   m := db.Lookup(mapping.GUID(info))
+//   if m != nil {
+//     pad := joystick.NewGamepad(js, m.GamepadMapping(js))
+//   }
+//
+package mapping
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/0xcafed00d/joystick"
+)
+
+// Input is a joystick input bound to a gamepad control, numbered the way SDL numbers them:
+// axis exclude hats, and hats are numbered separately
+type Input struct {
+	Type joystick.BindingType
+	// Index of the button, axis or hat
+	Index int
+	// For axis, the part of the axis that is used
+	Range joystick.AxisRange
+	// For axis, reverses the direction of the axis
+	Invert bool
+	// For hats, the directions that activate the control
+	HatMask int
+}
+
+// Mapping describes how the inputs of one controller model form a standard gamepad
+type Mapping struct {
+	GUID     string
+	Name     string
+	Platform string
+	Buttons  [joystick.GamepadButtonCount]Input
+	Axes     [joystick.GamepadAxisCount]Input
+}
+
+var buttonNames = map[string]joystick.GamepadButton{
+	"a":             joystick.GamepadSouth,
+	"b":             joystick.GamepadEast,
+	"x":             joystick.GamepadWest,
+	"y":             joystick.GamepadNorth,
+	"leftshoulder":  joystick.GamepadLeftShoulder,
+	"rightshoulder": joystick.GamepadRightShoulder,
+	"back":          joystick.GamepadBack,
+	"start":         joystick.GamepadStart,
+	"guide":         joystick.GamepadGuide,
+	"leftstick":     joystick.GamepadLeftStick,
+	"rightstick":    joystick.GamepadRightStick,
+	"dpup":          joystick.GamepadDPadUp,
+	"dpdown":        joystick.GamepadDPadDown,
+	"dpleft":        joystick.GamepadDPadLeft,
+	"dpright":       joystick.GamepadDPadRight,
+}
+
+var axisNames = map[string]joystick.GamepadAxis{
+	"leftx":        joystick.GamepadLeftX,
+	"lefty":        joystick.GamepadLeftY,
+	"rightx":       joystick.GamepadRightX,
+	"righty":       joystick.GamepadRightY,
+	"lefttrigger":  joystick.GamepadLeftTrigger,
+	"righttrigger": joystick.GamepadRightTrigger,
+}
+
+// Parse parses a single mapping line.
+//
+// Controls that have no equivalent in the standard gamepad layout, such as
+// paddles, and bindings to half of an output axis are ignored.
+func Parse(line string) (*Mapping, error) {
+	fields := strings.Split(strings.TrimSpace(line), ",")
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("invalid mapping %q", line)
+	}
+
+	guid := strings.ToLower(fields[0])
+	if _, err := hex.DecodeString(guid); err != nil || len(guid) != 32 {
+		return nil, fmt.Errorf("invalid GUID %q", fields[0])
+	}
+
+	m := &Mapping{GUID: guid, Name: fields[1]}
+
+	for _, field := range fields[2:] {
+		if field == "" {
+			continue
+		}
+		kv := strings.SplitN(field, ":", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid binding %q", field)
+		}
+		key, value := kv[0], kv[1]
+
+		if key == "platform" {
+			m.Platform = value
+			continue
+		}
+
+		button, isButton := buttonNames[key]
+		axis, isAxis := axisNames[key]
+		if !isButton && !isAxis {
+			continue
+		}
+
+		input, err := parseInput(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid binding %q: %v", field, err)
+		}
+
+		if isButton {
+			m.Buttons[button] = input
+		} else {
+			m.Axes[axis] = input
+		}
+	}
+	return m, nil
+}
+
+// parseInput parses an input such as b3, a2, +a2, -a2, a2~ or h0.4
+func parseInput(s string) (Input, error) {
+	var in Input
+
+	if strings.HasPrefix(s, "+") {
+		in.Range = joystick.PositiveHalf
+		s = s[1:]
+	} else if strings.HasPrefix(s, "-") {
+		in.Range = joystick.NegativeHalf
+		s = s[1:]
+	}
+	if strings.HasSuffix(s, "~") {
+		in.Invert = true
+		s = s[:len(s)-1]
+	}
+	if s == "" {
+		return in, fmt.Errorf("missing input")
+	}
+
+	var err error
+	switch s[0] {
+	case 'b':
+		in.Type = joystick.BindButton
+		in.Index, err = strconv.Atoi(s[1:])
+	case 'a':
+		in.Type = joystick.BindAxis
+		in.Index, err = strconv.Atoi(s[1:])
+	case 'h':
+		in.Type = joystick.BindHat
+		parts := strings.SplitN(s[1:], ".", 2)
+		if len(parts) != 2 {
+			return in, fmt.Errorf("invalid hat %q", s)
+		}
+		if in.Index, err = strconv.Atoi(parts[0]); err == nil {
+			in.HatMask, err = strconv.Atoi(parts[1])
+		}
+	default:
+		return in, fmt.Errorf("unknown input %q", s)
+	}
+	if err == nil && in.Index < 0 {
+		err = fmt.Errorf("negative index")
+	}
+	return in, err
+}
+
+// DB holds a set of mappings indexed by GUID
+type DB struct {
+	mappings map[string]*Mapping
+}
+
+// ParseDB reads mappings in the gamecontrollerdb.txt format. Blank lines and
+// lines starting with # are skipped. When a GUID appears more than once, the
+// mapping for the current platform is kept, otherwise the last one.
+func ParseDB(r io.Reader) (*DB, error) {
+	db := &DB{mappings: make(map[string]*Mapping)}
+
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		m, err := Parse(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNo, err)
+		}
+		db.Add(m)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Add adds a mapping to the DB
+func (db *DB) Add(m *Mapping) {
+	if db.mappings == nil {
+		db.mappings = make(map[string]*Mapping)
+	}
+	key := guidKey(m.GUID)
+	if old, ok := db.mappings[key]; ok && old.Platform == currentPlatform() && m.Platform != currentPlatform() {
+		return
+	}
+	db.mappings[key] = m
+}
+
+// Lookup returns the mapping for a GUID, or nil if there is none.
+// The CRC of the device name included in recent SDL GUIDs is ignored
+func (db *DB) Lookup(guid string) *Mapping {
+	return db.mappings[guidKey(guid)]
+}
+
+// Len returns the number of mappings in the DB
+func (db *DB) Len() int {
+	return len(db.mappings)
+}
+
+// guidKey normalises a GUID for lookup by clearing its name CRC
+func guidKey(guid string) string {
+	guid = strings.ToLower(guid)
+	if len(guid) == 32 {
+		guid = guid[:4] + "0000" + guid[8:]
+	}
+	return guid
+}
+
+func currentPlatform() string {
+	switch runtime.GOOS {
+	case "linux":
+		return "Linux"
+	case "windows":
+		return "Windows"
+	case "darwin":
+		return "Mac OS X"
+	}
+	return ""
+}
+
+// GUID computes the SDL GUID of a device from its bus, vendor, product and version,
+// the same way SDL does under linux. Devices with no vendor or product id are
+// identified by their name instead
+func GUID(info joystick.DeviceInfo) string {
+	var guid [16]byte
+
+	binary.LittleEndian.PutUint16(guid[0:], info.BusType)
+	if info.Vendor != 0 && info.Product != 0 {
+		binary.LittleEndian.PutUint16(guid[4:], info.Vendor)
+		binary.LittleEndian.PutUint16(guid[8:], info.Product)
+		binary.LittleEndian.PutUint16(guid[12:], info.Version)
+	} else {
+		copy(guid[4:15], info.Name)
+	}
+	return hex.EncodeToString(guid[:])
+}
+
+// GamepadMapping converts the mapping to the indices used by js.
+//
+// Under linux SDL numbers axis in ABS_ code order leaving out hats, so the axis
+// codes reported by js are used to find each axis and hat. For joysticks that
+// do not report codes, hats are assumed to be reported as pairs of axis following
+// the other axis, as the Windows and Mac OSX backends do.
+func (m *Mapping) GamepadMapping(js joystick.Joystick) *joystick.GamepadMapping {
+	axes, hats := m.axisLayout(js)
+
+	gm := &joystick.GamepadMapping{Name: m.Name}
+	for i, in := range m.Buttons {
+		gm.Buttons[i] = in.binding(axes, hats)
+	}
+	for i, in := range m.Axes {
+		gm.Axes[i] = in.binding(axes, hats)
+	}
+	return gm
+}
+
+// axisLayout returns the joystick axis index of each SDL axis, and of the horizontal axis of each SDL hat
+func (m *Mapping) axisLayout(js joystick.Joystick) (axes, hats []int) {
+	if cm, ok := js.(joystick.CodeMapper); ok {
+		for i, code := range cm.AxisCodes() {
+			if code >= joystick.AxisHat0X && code <= joystick.AxisHat3Y {
+				if (code-joystick.AxisHat0X)%2 == 0 {
+					hats = append(hats, i)
+				}
+				continue
+			}
+			axes = append(axes, i)
+		}
+		return axes, hats
+	}
+
+	hatCount := 0
+	for _, in := range append(m.Buttons[:], m.Axes[:]...) {
+		if in.Type == joystick.BindHat && in.Index >= hatCount {
+			hatCount = in.Index + 1
+		}
+	}
+
+	axisCount := js.AxisCount() - hatCount*2
+	for i := 0; i < axisCount; i++ {
+		axes = append(axes, i)
+	}
+	for i := 0; i < hatCount && axisCount >= 0; i++ {
+		hats = append(hats, axisCount+i*2)
+	}
+	return axes, hats
+}
+
+func (in Input) binding(axes, hats []int) joystick.Binding {
+	b := joystick.Binding{
+		Type:    in.Type,
+		Index:   in.Index,
+		Range:   in.Range,
+		Invert:  in.Invert,
+		HatMask: in.HatMask,
+	}
+	switch in.Type {
+	case joystick.BindAxis:
+		if in.Index >= len(axes) {
+			return joystick.Binding{}
+		}
+		b.Index = axes[in.Index]
+	case joystick.BindHat:
+		if in.Index >= len(hats) {
+			return joystick.Binding{}
+		}
+		b.Index = hats[in.Index]
+	}
+	return b
+}
+
+// Map converts the state of js to the standard gamepad layout
+func (m *Mapping) Map(js joystick.Joystick, state joystick.State) joystick.GamepadState {
+	return m.GamepadMapping(js).Map(state)
+}
@@ -0,0 +1,241 @@
+package mapping
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/0xcafed00d/joystick"
+)
+
+func TestParseInput(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Input
+	}{
+		{"b3", Input{Type: joystick.BindButton, Index: 3}},
+		{"b12", Input{Type: joystick.BindButton, Index: 12}},
+		{"a2", Input{Type: joystick.BindAxis, Index: 2}},
+		{"+a2", Input{Type: joystick.BindAxis, Index: 2, Range: joystick.PositiveHalf}},
+		{"-a2", Input{Type: joystick.BindAxis, Index: 2, Range: joystick.NegativeHalf}},
+		{"a2~", Input{Type: joystick.BindAxis, Index: 2, Invert: true}},
+		{"-a5~", Input{Type: joystick.BindAxis, Index: 5, Range: joystick.NegativeHalf, Invert: true}},
+		{"h0.4", Input{Type: joystick.BindHat, Index: 0, HatMask: joystick.HatDown}},
+		{"h1.8", Input{Type: joystick.BindHat, Index: 1, HatMask: joystick.HatLeft}},
+	}
+	for _, test := range tests {
+		got, err := parseInput(test.input)
+		if err != nil {
+			t.Errorf("parseInput(%q): %v", test.input, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("parseInput(%q) = %+v, want %+v", test.input, got, test.want)
+		}
+	}
+
+	for _, input := range []string{"", "+", "~", "b", "bx", "a-1", "x1", "h0", "h.1", "h0.x", "3"} {
+		if got, err := parseInput(input); err == nil {
+			t.Errorf("parseInput(%q) = %+v, expected an error", input, got)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	m, err := Parse("030000005E0400008E02000010010000,Xbox 360 Controller,a:b0,b:b1,x:b2,y:b3," +
+		"leftx:a0,lefty:a1,lefttrigger:a2,rightx:a3,righty:a4,righttrigger:a5," +
+		"dpup:h0.1,dpdown:h0.4,dpleft:h0.8,dpright:h0.2,paddle1:b11,platform:Linux,\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.GUID != "030000005e0400008e02000010010000" || m.Name != "Xbox 360 Controller" || m.Platform != "Linux" {
+		t.Errorf("got GUID %q, name %q, platform %q", m.GUID, m.Name, m.Platform)
+	}
+	if want := (Input{Type: joystick.BindButton, Index: 3}); m.Buttons[joystick.GamepadNorth] != want {
+		t.Errorf("got north %+v, want %+v", m.Buttons[joystick.GamepadNorth], want)
+	}
+	if want := (Input{Type: joystick.BindHat, Index: 0, HatMask: joystick.HatRight}); m.Buttons[joystick.GamepadDPadRight] != want {
+		t.Errorf("got dpad right %+v, want %+v", m.Buttons[joystick.GamepadDPadRight], want)
+	}
+	if want := (Input{Type: joystick.BindAxis, Index: 5}); m.Axes[joystick.GamepadRightTrigger] != want {
+		t.Errorf("got right trigger %+v, want %+v", m.Axes[joystick.GamepadRightTrigger], want)
+	}
+	if m.Buttons[joystick.GamepadGuide].Type != joystick.BindNone {
+		t.Errorf("got guide %+v, want unbound", m.Buttons[joystick.GamepadGuide])
+	}
+
+	for _, line := range []string{
+		"",
+		"030000005e0400008e02000010010000",
+		"030000005e0400008e020000100100,Short GUID,a:b0",
+		"030000005e0400008e0200001001000g,Bad GUID,a:b0",
+		"030000005e0400008e02000010010000,No Colon,a",
+		"030000005e0400008e02000010010000,Bad Input,a:q0",
+		"030000005e0400008e02000010010000,Empty Input,a:",
+	} {
+		if _, err := Parse(line); err == nil {
+			t.Errorf("Parse(%q): expected an error", line)
+		}
+	}
+}
+
+func TestParseDB(t *testing.T) {
+	db, err := ParseDB(strings.NewReader(`
+# Game Controller DB
+03000000de280000ff11000001000000,Other Platform,a:b1,platform:Mac OS X,
+03000000de280000ff11000001000000,Current Platform,a:b0,platform:` + currentPlatform() + `,
+03000000de280000ff11000001000000,Later Other Platform,a:b2,platform:Windows,
+
+0300d1f2de280000fc11000001000000,Named Pad,a:b0,
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.Len() != 2 {
+		t.Errorf("got %d mappings, want 2", db.Len())
+	}
+	if m := db.Lookup("03000000DE280000FF11000001000000"); m == nil || m.Name != "Current Platform" {
+		t.Errorf("got %+v, want the mapping for the current platform", m)
+	}
+	// the CRC of the name, in bytes 2 and 3, is ignored
+	if m := db.Lookup("03000000de280000fc11000001000000"); m == nil || m.Name != "Named Pad" {
+		t.Errorf("got %+v, want Named Pad", m)
+	}
+	if m := db.Lookup("0300000000000000fc11000001000000"); m != nil {
+		t.Errorf("got %+v for an unknown GUID", m)
+	}
+
+	if _, err := ParseDB(strings.NewReader("03000000de280000ff11000001000000,Pad,a:b0\nbad line\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("got error %v, want an error on line 2", err)
+	}
+}
+
+func TestGUID(t *testing.T) {
+	tests := []struct {
+		info joystick.DeviceInfo
+		want string
+	}{
+		// USB Xbox 360 controller
+		{joystick.DeviceInfo{BusType: 0x03, Vendor: 0x045e, Product: 0x028e, Version: 0x0110, Name: "Microsoft X-Box 360 pad"},
+			"030000005e0400008e02000010010000"},
+		// Bluetooth DualShock 4
+		{joystick.DeviceInfo{BusType: 0x05, Vendor: 0x054c, Product: 0x05c4, Version: 0x8100, Name: "Wireless Controller"},
+			"050000004c050000c405000000810000"},
+		// no vendor or product id, identified by name
+		{joystick.DeviceInfo{BusType: 0x05, Name: "COMMAND S"},
+			"05000000434f4d4d414e442053000000"},
+		// names are truncated to 11 bytes
+		{joystick.DeviceInfo{BusType: 0x19, Name: "Generic Virtual Joystick"},
+			"1900000047656e657269632056697200"},
+	}
+	for _, test := range tests {
+		if got := GUID(test.info); got != test.want {
+			t.Errorf("GUID(%+v) = %s, want %s", test.info, got, test.want)
+		}
+	}
+}
+
+// codedJoystick is a VirtualJoystick reporting axis codes, like the linux backend
+type codedJoystick struct {
+	*joystick.VirtualJoystick
+	axisCodes []joystick.AxisCode
+}
+
+func (js codedJoystick) AxisCodes() []joystick.AxisCode {
+	return js.axisCodes
+}
+
+func (js codedJoystick) ButtonCodes() []joystick.ButtonCode {
+	return nil
+}
+
+const testMapping = "030000005e0400008e02000010010000,Test Pad,a:b0,b:b1,leftx:a0,lefty:a1~," +
+	"lefttrigger:+a2,righttrigger:-a2,dpup:h0.1,dpdown:h0.4,dpleft:h0.8,dpright:h0.2,"
+
+func TestMap(t *testing.T) {
+	m, err := Parse(testMapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// without axis codes, hats follow the other axis
+	vj := joystick.NewVirtualJoystick("Test Pad", 5, 2)
+	check := func(name string, want joystick.GamepadState) {
+		t.Helper()
+		state, err := vj.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := m.Map(vj, state); got != want {
+			t.Errorf("%s: got %+v, want %+v", name, got, want)
+		}
+	}
+
+	check("centered", joystick.GamepadState{})
+
+	vj.SetAxis(0, 1000)
+	vj.SetAxis(1, 2000)
+	vj.SetAxis(2, 32767)
+	vj.SetAxis(3, -32767)
+	vj.SetAxis(4, -32767)
+	vj.Press(1)
+
+	var want joystick.GamepadState
+	want.Buttons[joystick.GamepadEast] = true
+	want.Buttons[joystick.GamepadDPadUp] = true
+	want.Buttons[joystick.GamepadDPadLeft] = true
+	want.Axes[joystick.GamepadLeftX] = 1000
+	// inverted axis
+	want.Axes[joystick.GamepadLeftY] = -2000
+	// each trigger uses half of axis 2
+	want.Axes[joystick.GamepadLeftTrigger] = 32767
+	want.Axes[joystick.GamepadRightTrigger] = 0
+	check("deflected", want)
+
+	vj.SetAxis(2, -32767)
+	vj.SetAxis(3, 32767)
+	vj.SetAxis(4, 32767)
+	want.Buttons[joystick.GamepadDPadUp] = false
+	want.Buttons[joystick.GamepadDPadLeft] = false
+	want.Buttons[joystick.GamepadDPadDown] = true
+	want.Buttons[joystick.GamepadDPadRight] = true
+	want.Axes[joystick.GamepadLeftTrigger] = 0
+	want.Axes[joystick.GamepadRightTrigger] = 32767
+	check("reversed", want)
+}
+
+func TestGamepadMappingAxisCodes(t *testing.T) {
+	m, err := Parse(testMapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// SDL numbers the axis in code order leaving out the hats
+	js := codedJoystick{
+		joystick.NewVirtualJoystick("Test Pad", 5, 2),
+		[]joystick.AxisCode{joystick.AxisX, joystick.AxisHat0X, joystick.AxisHat0Y, joystick.AxisY, joystick.AxisZ},
+	}
+	gm := m.GamepadMapping(js)
+
+	want := map[joystick.GamepadAxis]joystick.Binding{
+		joystick.GamepadLeftX:        {Type: joystick.BindAxis, Index: 0},
+		joystick.GamepadLeftY:        {Type: joystick.BindAxis, Index: 3, Invert: true},
+		joystick.GamepadLeftTrigger:  {Type: joystick.BindAxis, Index: 4, Range: joystick.PositiveHalf},
+		joystick.GamepadRightTrigger: {Type: joystick.BindAxis, Index: 4, Range: joystick.NegativeHalf},
+		joystick.GamepadRightX:       {},
+	}
+	for axis, b := range want {
+		if gm.Axes[axis] != b {
+			t.Errorf("axis %d: got %+v, want %+v", axis, gm.Axes[axis], b)
+		}
+	}
+	if b := gm.Buttons[joystick.GamepadDPadUp]; b != (joystick.Binding{Type: joystick.BindHat, Index: 1, HatMask: joystick.HatUp}) {
+		t.Errorf("got dpad up %+v, want hat at axis 1", b)
+	}
+
+	// bindings to inputs the joystick does not have are dropped
+	js.axisCodes = js.axisCodes[:1]
+	if b := m.GamepadMapping(js).Axes[joystick.GamepadLeftY]; b.Type != joystick.BindNone {
+		t.Errorf("got %+v for a missing axis, want unbound", b)
+	}
+}
@@ -0,0 +1,28 @@
+package joystick
+
+// OpenByName opens the first joystick, in id order, whose name is name
+func OpenByName(name string) (Joystick, error) {
+	return OpenMatching(func(info DeviceInfo) bool {
+		return info.Name == name
+	})
+}
+
+// OpenMatching opens the first joystick, in id order, for which match returns true.
+// Returns an error matching ErrNotFound if there is none
+func OpenMatching(match func(DeviceInfo) bool) (Joystick, error) {
+	devices, err := Enumerate()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, info := range devices {
+		if !match(info) {
+			continue
+		}
+		if info.Path != "" {
+			return OpenPath(info.Path)
+		}
+		return Open(info.ID)
+	}
+	return nil, &OpenError{Op: "open", Err: ErrNotFound}
+}
@@ -0,0 +1,18 @@
+// +build !linux
+
+package joystick
+
+import (
+	"errors"
+	"io"
+)
+
+// OpenPath opens the joystick device node at path. It is only supported under linux
+func OpenPath(path string) (Joystick, error) {
+	return nil, &OpenError{Path: path, Op: "open", Err: errors.New("opening by path is only supported on linux")}
+}
+
+// OpenReader returns a Joystick that reads joydev events from r. It is only supported under linux
+func OpenReader(r io.ReadCloser, info DeviceInfo) (Joystick, error) {
+	return nil, &OpenError{Op: "open", Err: errors.New("reading joydev events is only supported on linux")}
+}
@@ -0,0 +1,51 @@
+package joystick
+
+import (
+	"context"
+	"time"
+)
+
+// Interface ReadyWaiter is implemented by joysticks whose initial state arrives after Open returns.
+// Until they are ready, Read may report every axis centered and every button released
+// whatever the actual state of the device.
+//
+// Under linux, joysticks opened through joydev implement ReadyWaiter. Other joysticks know
+// their state as soon as they are opened
+type ReadyWaiter interface {
+	// Ready returns true once the initial state of the joystick is known
+	Ready() bool
+	// WaitReady waits until the initial state of the joystick is known. Returns early with
+	// the error if the joystick is disconnected or closed, or if ctx is done
+	WaitReady(ctx context.Context) error
+}
+
+// WaitReady waits until the initial state of js is known. Returns straight away
+// for joysticks that do not implement ReadyWaiter
+func WaitReady(ctx context.Context, js Joystick) error {
+	if rw, ok := js.(ReadyWaiter); ok {
+		return rw.WaitReady(ctx)
+	}
+	return nil
+}
+
+// The function OpenReady uses to open the joystick. Tests replace it
+var readyOpen = Open
+
+// OpenReady opens the joystick like Open, then waits up to timeout for its initial state
+// to be known, so that the first Read reports the actual state of the device.
+// The joystick is closed and an error returned if it is not ready in time
+func OpenReady(id int, timeout time.Duration) (Joystick, error) {
+	js, err := readyOpen(id)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := WaitReady(ctx, js); err != nil {
+		js.Close()
+		return nil, &OpenError{Op: "WaitReady", Err: err}
+	}
+	return js, nil
+}
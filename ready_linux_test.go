@@ -0,0 +1,118 @@
+// +build linux
+
+package joystick
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// initBurst returns the init events a joydev device sends after open, for axisCount axis and buttonCount buttons
+func initBurst(axisCount, buttonCount int) []byte {
+	var events []event
+	for i := 0; i < axisCount; i++ {
+		events = append(events, event{Type: _JS_EVENT_AXIS | _JS_EVENT_INIT, Number: uint8(i)})
+	}
+	for i := 0; i < buttonCount; i++ {
+		events = append(events, event{Type: _JS_EVENT_BUTTON | _JS_EVENT_INIT, Number: uint8(i)})
+	}
+	return encodeJsEvents(events...)
+}
+
+func TestWaitReady(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// ready after the init burst
+	js, w := newPipeJoystick(t, joydevInfo{axisCount: 2, buttonCount: 2})
+	done := make(chan error, 1)
+	go func() {
+		done <- WaitReady(ctx, js)
+	}()
+	b := initBurst(2, 2)
+	if _, err := w.Write(b[:3*_JS_EVENT_SIZE]); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("WaitReady returned %v before the end of the init burst", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	if _, err := w.Write(b[3*_JS_EVENT_SIZE:]); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil || !js.Ready() {
+		t.Errorf("got %v, ready %v after the init burst", err, js.Ready())
+	}
+
+	// the deadline expires first
+	js, _ = newPipeJoystick(t, joydevInfo{axisCount: 2, buttonCount: 2})
+	short, cancelShort := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancelShort()
+	if err := WaitReady(short, js); err != context.DeadlineExceeded || js.Ready() {
+		t.Errorf("got %v, ready %v, want DeadlineExceeded", err, js.Ready())
+	}
+
+	// the device is disconnected while waiting
+	js, w = newPipeJoystick(t, joydevInfo{axisCount: 2, buttonCount: 2})
+	go func() {
+		done <- WaitReady(ctx, js)
+	}()
+	if _, err := w.Write(b[:_JS_EVENT_SIZE]); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	w.Close()
+	if err := <-done; !errors.Is(err, ErrDisconnected) {
+		t.Errorf("got %v, want ErrDisconnected", err)
+	}
+
+	// joysticks that are not ReadyWaiters are ready when opened
+	if err := WaitReady(short, NewVirtualJoystick("pad", 1, 1)); err != nil {
+		t.Errorf("got %v for a virtual joystick", err)
+	}
+}
+
+func TestOpenReady(t *testing.T) {
+	var js *joystickImpl
+	oldOpen := readyOpen
+	t.Cleanup(func() {
+		readyOpen = oldOpen
+	})
+	readyOpen = func(id int) (Joystick, error) {
+		return js, nil
+	}
+
+	// the init burst is sent before OpenReady waits for it
+	js, w := newPipeJoystick(t, joydevInfo{axisCount: 1, buttonCount: 1})
+	if _, err := w.Write(initBurst(1, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := OpenReady(0, 5*time.Second); err != nil || got != js {
+		t.Errorf("got %v, %v, want the ready joystick", got, err)
+	}
+
+	// the joystick is closed when it is not ready in time
+	js, _ = newPipeJoystick(t, joydevInfo{axisCount: 1, buttonCount: 1})
+	if _, err := OpenReady(0, 20*time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want DeadlineExceeded", err)
+	}
+	if _, err := js.Read(); err != ErrClosed {
+		t.Errorf("got %v reading the joystick that was not ready, want ErrClosed", err)
+	}
+
+	js, w = newPipeJoystick(t, joydevInfo{axisCount: 1, buttonCount: 1})
+	w.Close()
+	if _, err := OpenReady(0, 5*time.Second); !errors.Is(err, ErrDisconnected) {
+		t.Errorf("got %v, want ErrDisconnected", err)
+	}
+
+	readyOpen = func(id int) (Joystick, error) {
+		return nil, &OpenError{Op: "open", Err: ErrNotFound}
+	}
+	if _, err := OpenReady(0, time.Second); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,247 @@
+package joystick
+
+import (
+	"sync"
+	"time"
+)
+
+// ConnectionState reports whether a ReconnectingJoystick currently has its device open
+type ConnectionState int
+
+const (
+	// Connected means the device is open and being read
+	Connected ConnectionState = iota
+	// Disconnected means the device has been lost and is waiting to be reconnected
+	Disconnected
+)
+
+func (c ConnectionState) String() string {
+	switch c {
+	case Connected:
+		return "Connected"
+	case Disconnected:
+		return "Disconnected"
+	}
+	return "Unknown"
+}
+
+// The functions a ReconnectingJoystick uses to find and open its device. Tests replace them
+var (
+	reconnectEnumerate = Enumerate
+	reconnectOpen      = Open
+)
+
+// ReconnectOptions controls the behaviour of a ReconnectingJoystick
+type ReconnectOptions struct {
+	// Minimum time between attempts to find the device again. Defaults to 1 second
+	RetryInterval time.Duration
+	// If not nil, called from Read() whenever the connection state changes
+	OnStateChange func(ConnectionState)
+}
+
+// ReconnectingJoystick is a Joystick that reopens its device automatically when it
+// is disconnected and connected again, even if it comes back with a different id.
+//
+// The device is recognised by its vendor id, product id, serial number and name.
+type ReconnectingJoystick struct {
+	mutex     sync.Mutex
+	info      DeviceInfo
+	opts      ReconnectOptions
+	js        Joystick
+	state     State
+	lastErr   error
+	lastRetry time.Time
+	connState ConnectionState
+	closed    bool
+	seqBase   uint64
+}
+
+// OpenReconnecting opens the Joystick with the supplied id, as Open() does,
+// and wraps it in a ReconnectingJoystick
+func OpenReconnecting(id int, opts ReconnectOptions) (*ReconnectingJoystick, error) {
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = time.Second
+	}
+
+	devices, err := reconnectEnumerate()
+	if err != nil {
+		return nil, err
+	}
+
+	rj := &ReconnectingJoystick{opts: opts}
+	found := false
+	for _, info := range devices {
+		if info.ID == id {
+			rj.info = info
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, &OpenError{Op: "open", Err: ErrNotFound}
+	}
+
+	rj.js, err = reconnectOpen(id)
+	if err != nil {
+		return nil, err
+	}
+	rj.info.AxisCount = rj.js.AxisCount()
+	rj.info.ButtonCount = rj.js.ButtonCount()
+	rj.state.AxisData = make([]int, rj.info.AxisCount)
+	rj.state.ButtonData = make([]bool, rj.info.ButtonCount)
+
+	return rj, nil
+}
+
+// Info returns the information on the device, as it was when it was last connected
+func (rj *ReconnectingJoystick) Info() DeviceInfo {
+	rj.mutex.Lock()
+	defer rj.mutex.Unlock()
+	return rj.info
+}
+
+// ConnectionState returns the current connection state
+func (rj *ReconnectingJoystick) ConnectionState() ConnectionState {
+	rj.mutex.Lock()
+	defer rj.mutex.Unlock()
+	return rj.connState
+}
+
+func (rj *ReconnectingJoystick) AxisCount() int {
+	rj.mutex.Lock()
+	defer rj.mutex.Unlock()
+	return rj.info.AxisCount
+}
+
+func (rj *ReconnectingJoystick) ButtonCount() int {
+	rj.mutex.Lock()
+	defer rj.mutex.Unlock()
+	return rj.info.ButtonCount
+}
+
+func (rj *ReconnectingJoystick) Name() string {
+	rj.mutex.Lock()
+	defer rj.mutex.Unlock()
+	return rj.info.Name
+}
+
+// Read returns the current State of the joystick.
+//
+// While the device is disconnected, Read returns the last error reported by the
+// device and a State with every axis centered and every button released.
+// Each call then tries to reopen the device, at most once per RetryInterval.
+func (rj *ReconnectingJoystick) Read() (State, error) {
+	var state State
+	err := rj.ReadInto(&state)
+	return state, err
+}
+
+func (rj *ReconnectingJoystick) ReadInto(state *State) error {
+	rj.mutex.Lock()
+	prevState := rj.connState
+	err := rj.readInto(state)
+	connState := rj.connState
+	rj.mutex.Unlock()
+
+	if connState != prevState && rj.opts.OnStateChange != nil {
+		rj.opts.OnStateChange(connState)
+	}
+
+	return err
+}
+
+// readInto implements ReadInto. Must be called with the mutex held
+func (rj *ReconnectingJoystick) readInto(state *State) error {
+	if rj.js == nil && !rj.closed && time.Since(rj.lastRetry) >= rj.opts.RetryInterval {
+		rj.lastRetry = time.Now()
+		rj.reconnect()
+	}
+
+	if rj.js != nil {
+		err := ReadInto(rj.js, state)
+		if err == nil {
+			// keep Sequence increasing across reconnections
+			state.Sequence += rj.seqBase
+			rj.state.Sequence = state.Sequence
+			rj.state.Timestamp = state.Timestamp
+			return nil
+		}
+		rj.js.Close()
+		rj.js = nil
+		rj.lastErr = err
+		rj.lastRetry = time.Now()
+		rj.state = State{
+			AxisData:   make([]int, len(rj.state.AxisData)),
+			ButtonData: make([]bool, len(rj.state.ButtonData)),
+			Sequence:   rj.state.Sequence,
+		}
+		rj.state.markChanged(time.Now())
+		rj.connState = Disconnected
+	}
+
+	rj.state.copyInto(state)
+	return rj.lastErr
+}
+
+// Close releases the joystick resource. The device will not be reopened after Close
+func (rj *ReconnectingJoystick) Close() error {
+	rj.mutex.Lock()
+	defer rj.mutex.Unlock()
+
+	var err error
+	if rj.js != nil {
+		err = rj.js.Close()
+		rj.js = nil
+	}
+	rj.closed = true
+	rj.lastErr = ErrClosed
+	return err
+}
+
+// reconnect looks for the device and opens it. Must be called with the mutex held
+func (rj *ReconnectingJoystick) reconnect() {
+	devices, err := reconnectEnumerate()
+	if err != nil {
+		return
+	}
+
+	var match *DeviceInfo
+	for i := range devices {
+		if !rj.matches(&devices[i]) {
+			continue
+		}
+		// prefer the id the device had before
+		if match == nil || devices[i].ID == rj.info.ID {
+			match = &devices[i]
+		}
+	}
+	if match == nil {
+		return
+	}
+
+	js, err := reconnectOpen(match.ID)
+	if err != nil {
+		return
+	}
+
+	rj.js = js
+	rj.info = *match
+	rj.info.AxisCount = js.AxisCount()
+	rj.info.ButtonCount = js.ButtonCount()
+	rj.state = State{
+		AxisData:   make([]int, rj.info.AxisCount),
+		ButtonData: make([]bool, rj.info.ButtonCount),
+		Timestamp:  rj.state.Timestamp,
+		Sequence:   rj.state.Sequence,
+	}
+	rj.seqBase = rj.state.Sequence + 1
+	rj.lastErr = nil
+	rj.connState = Connected
+}
+
+func (rj *ReconnectingJoystick) matches(info *DeviceInfo) bool {
+	return info.Vendor == rj.info.Vendor &&
+		info.Product == rj.info.Product &&
+		info.Serial == rj.info.Serial &&
+		info.Name == rj.info.Name
+}
@@ -0,0 +1,171 @@
+package joystick
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeDevices replaces the devices seen by ReconnectingJoystick with virtual joysticks
+type fakeDevices struct {
+	devices    map[int]*VirtualJoystick
+	infos      map[int]DeviceInfo
+	enumerates int
+}
+
+func newFakeDevices(t *testing.T) *fakeDevices {
+	d := &fakeDevices{devices: map[int]*VirtualJoystick{}, infos: map[int]DeviceInfo{}}
+	oldEnumerate, oldOpen := reconnectEnumerate, reconnectOpen
+	t.Cleanup(func() {
+		reconnectEnumerate, reconnectOpen = oldEnumerate, oldOpen
+	})
+	reconnectEnumerate = d.enumerate
+	reconnectOpen = d.open
+	return d
+}
+
+// plug connects a device under id, returning the joystick Open will return for it
+func (d *fakeDevices) plug(id int, info DeviceInfo) *VirtualJoystick {
+	info.ID = id
+	vj := NewVirtualJoystick(info.Name, 2, 2)
+	d.devices[id] = vj
+	d.infos[id] = info
+	return vj
+}
+
+// unplug disconnects the device with the id
+func (d *fakeDevices) unplug(id int) {
+	d.devices[id].Disconnect()
+	delete(d.devices, id)
+	delete(d.infos, id)
+}
+
+func (d *fakeDevices) enumerate() ([]DeviceInfo, error) {
+	d.enumerates++
+	var devices []DeviceInfo
+	for id := 0; id < 10; id++ {
+		if info, ok := d.infos[id]; ok {
+			devices = append(devices, info)
+		}
+	}
+	return devices, nil
+}
+
+func (d *fakeDevices) open(id int) (Joystick, error) {
+	vj, ok := d.devices[id]
+	if !ok {
+		return nil, &OpenError{Op: "open", Err: ErrNotFound}
+	}
+	return vj, nil
+}
+
+func TestReconnectingJoystick(t *testing.T) {
+	d := newFakeDevices(t)
+	pad := DeviceInfo{Name: "pad", Vendor: 0x045e, Product: 0x028e, Serial: "1234"}
+	d.plug(0, DeviceInfo{Name: "pad", Vendor: 0x045e, Product: 0x028e, Serial: "5678"})
+	vj := d.plug(1, pad)
+
+	var changes []ConnectionState
+	rj, err := OpenReconnecting(1, ReconnectOptions{
+		RetryInterval: 50 * time.Millisecond,
+		OnStateChange: func(c ConnectionState) {
+			changes = append(changes, c)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var last uint64
+	// read checks that Sequence keeps increasing when the state changes
+	read := func(wantErr error) State {
+		t.Helper()
+		state, err := rj.Read()
+		if !errors.Is(err, wantErr) {
+			t.Fatalf("got error %v, want %v", err, wantErr)
+		}
+		if state.Sequence < last {
+			t.Fatalf("Sequence went from %d back to %d", last, state.Sequence)
+		}
+		last = state.Sequence
+		return state
+	}
+
+	vj.SetAxis(0, 1000)
+	vj.Press(1)
+	state := read(nil)
+	if !reflect.DeepEqual(state.AxisData, []int{1000, 0}) || !state.Pressed(1) || rj.ConnectionState() != Connected {
+		t.Errorf("got axis %v, buttons %v, %v", state.AxisData, state.ButtonData, rj.ConnectionState())
+	}
+	before := state.Sequence
+
+	// the last state is dropped when the device is lost
+	d.unplug(1)
+	state = read(ErrDisconnected)
+	if !reflect.DeepEqual(state.AxisData, []int{0, 0}) || state.Pressed(1) || state.Sequence <= before {
+		t.Errorf("got axis %v, buttons %v, sequence %d after disconnection", state.AxisData, state.ButtonData, state.Sequence)
+	}
+	if rj.ConnectionState() != Disconnected {
+		t.Errorf("got %v after disconnection", rj.ConnectionState())
+	}
+
+	// retries are limited to one per RetryInterval
+	vj = d.plug(2, pad)
+	enumerates := d.enumerates
+	read(ErrDisconnected)
+	if d.enumerates != enumerates {
+		t.Errorf("device looked for %d times before the retry interval", d.enumerates-enumerates)
+	}
+
+	// the device comes back under another id, with a state that starts from Sequence 0
+	time.Sleep(60 * time.Millisecond)
+	vj.SetAxis(1, -500)
+	state = read(nil)
+	if !reflect.DeepEqual(state.AxisData, []int{0, -500}) || rj.ConnectionState() != Connected || rj.Info().ID != 2 {
+		t.Errorf("got axis %v, %v, id %d after reconnection", state.AxisData, rj.ConnectionState(), rj.Info().ID)
+	}
+	seq := state.Sequence
+	vj.SetAxis(0, 7)
+	if state = read(nil); state.Sequence <= seq {
+		t.Errorf("Sequence %d not increased by a change after reconnection, was %d", state.Sequence, seq)
+	}
+
+	// the previous id is preferred when the device appears more than once
+	d.unplug(2)
+	read(ErrDisconnected)
+	d.plug(1, pad)
+	vj = d.plug(2, pad)
+	time.Sleep(60 * time.Millisecond)
+	read(nil)
+	if rj.Info().ID != 2 {
+		t.Errorf("reconnected to id %d, want the previous id 2", rj.Info().ID)
+	}
+
+	if want := []ConnectionState{Disconnected, Connected, Disconnected, Connected}; !reflect.DeepEqual(changes, want) {
+		t.Errorf("got state changes %v, want %v", changes, want)
+	}
+
+	// Close closes the device, and stops the retries
+	if err := rj.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := vj.Read(); err != ErrClosed {
+		t.Errorf("got %v reading the device after Close, want ErrClosed", err)
+	}
+	enumerates = d.enumerates
+	time.Sleep(60 * time.Millisecond)
+	read(ErrClosed)
+	if d.enumerates != enumerates {
+		t.Error("device looked for after Close")
+	}
+}
+
+func TestOpenReconnectingNotFound(t *testing.T) {
+	d := newFakeDevices(t)
+	d.plug(0, DeviceInfo{Name: "pad"})
+
+	if _, err := OpenReconnecting(1, ReconnectOptions{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+}
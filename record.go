@@ -0,0 +1,273 @@
+package joystick
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Recordings start with a header:
+//
+//   magic          [4]byte  "JSRC"
+//   format version uint16
+//   bus type, vendor, product, version  uint16 each
+//   axis count, button count            uint16 each
+//   driver version uint32
+//   name, serial   uint16 length followed by the bytes of the string
+//
+// followed by one record per event, laid out like the linux js_event with a 16 bit number:
+//
+//   time   uint32  milliseconds since the first event after the initial state, from the event timestamps
+//   value  int16
+//   type   uint8   1 = button, 2 = axis, 0x80 set on the events recording the initial state
+//   number uint16
+//
+// Every value is little endian.
+
+const (
+	recordVersion  = 1
+	recordTypeInit = 0x80
+	recordSize     = 9
+)
+
+var recordMagic = [4]byte{'J', 'S', 'R', 'C'}
+
+type recordHeader struct {
+	Magic         [4]byte
+	FormatVersion uint16
+	BusType       uint16
+	Vendor        uint16
+	Product       uint16
+	Version       uint16
+	AxisCount     uint16
+	ButtonCount   uint16
+	DriverVersion uint32
+}
+
+func writeRecordHeader(w io.Writer, info DeviceInfo) error {
+	hdr := recordHeader{
+		Magic:         recordMagic,
+		FormatVersion: recordVersion,
+		BusType:       info.BusType,
+		Vendor:        info.Vendor,
+		Product:       info.Product,
+		Version:       info.Version,
+		AxisCount:     uint16(info.AxisCount),
+		ButtonCount:   uint16(info.ButtonCount),
+		DriverVersion: info.DriverVersion,
+	}
+	if err := binary.Write(w, binary.LittleEndian, &hdr); err != nil {
+		return err
+	}
+	for _, s := range []string{info.Name, info.Serial} {
+		if len(s) > 0xffff {
+			s = s[:0xffff]
+		}
+		if err := binary.Write(w, binary.LittleEndian, uint16(len(s))); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readRecordHeader(r io.Reader) (DeviceInfo, error) {
+	var hdr recordHeader
+	if err := binary.Read(r, binary.LittleEndian, &hdr); err != nil {
+		return DeviceInfo{}, err
+	}
+	if hdr.Magic != recordMagic {
+		return DeviceInfo{}, errors.New("not a joystick recording")
+	}
+	if hdr.FormatVersion != recordVersion {
+		return DeviceInfo{}, fmt.Errorf("unsupported recording version %d", hdr.FormatVersion)
+	}
+
+	info := DeviceInfo{
+		BusType:       hdr.BusType,
+		Vendor:        hdr.Vendor,
+		Product:       hdr.Product,
+		Version:       hdr.Version,
+		AxisCount:     int(hdr.AxisCount),
+		ButtonCount:   int(hdr.ButtonCount),
+		DriverVersion: hdr.DriverVersion,
+	}
+	for _, s := range []*string{&info.Name, &info.Serial} {
+		var n uint16
+		if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+			return info, err
+		}
+		b := make([]byte, n)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return info, err
+		}
+		*s = string(b)
+	}
+	return info, nil
+}
+
+func writeRecord(w io.Writer, ev Event) error {
+	var b [recordSize]byte
+	typ := uint8(ev.Type)
+	if ev.Init {
+		typ |= recordTypeInit
+	}
+	binary.LittleEndian.PutUint32(b[0:], ev.Time)
+	binary.LittleEndian.PutUint16(b[4:], uint16(int16(clampAxis(ev.Value))))
+	b[6] = typ
+	binary.LittleEndian.PutUint16(b[7:], uint16(ev.Number))
+	_, err := w.Write(b[:])
+	return err
+}
+
+// readRecord reads the next event. Returns io.EOF at the end of the recording
+func readRecord(r io.Reader) (Event, error) {
+	var b [recordSize]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return Event{}, errors.New("truncated recording")
+		}
+		return Event{}, err
+	}
+	ev := Event{
+		Time:   binary.LittleEndian.Uint32(b[0:]),
+		Value:  int(int16(binary.LittleEndian.Uint16(b[4:]))),
+		Type:   EventType(b[6] &^ recordTypeInit),
+		Init:   b[6]&recordTypeInit != 0,
+		Number: int(binary.LittleEndian.Uint16(b[7:])),
+	}
+	if ev.Type != EventButton && ev.Type != EventAxis {
+		return ev, fmt.Errorf("invalid event type %d in recording", ev.Type)
+	}
+	return ev, nil
+}
+
+// statePeeker is implemented by joysticks that can copy their state without restarting
+// the counts of presses and releases, which belong to the program reading the joystick
+type statePeeker interface {
+	peekState(state *State) error
+}
+
+// peekState returns the state of js, without consuming its presses and releases when js
+// implements statePeeker
+func peekState(js Joystick) (State, error) {
+	var state State
+	if p, ok := js.(statePeeker); ok {
+		err := p.peekState(&state)
+		return state, err
+	}
+	return js.Read()
+}
+
+// Recorder writes every event of a joystick to a recording, that can be replayed with OpenReplay
+type Recorder struct {
+	mutex   sync.Mutex
+	w       *bufio.Writer
+	started bool
+	// timestamp of the first event, recorded as 0
+	start   uint32
+	stopped bool
+	err     error
+}
+
+// NewRecorder starts recording js, which must implement EventReader, to w.
+// info is stored in the header of the recording; its Name, AxisCount and ButtonCount
+// are taken from js when not set. The current state of js is recorded first, as init events,
+// without restarting the counts of presses and releases seen by the program reading js.
+// Events keep their timestamps, relative to the first event recorded.
+// Recording stops when Close is called, or when js is closed or disconnected
+func NewRecorder(w io.Writer, js Joystick, info DeviceInfo) (*Recorder, error) {
+	er, ok := js.(EventReader)
+	if !ok {
+		return nil, errors.New("joystick does not report events")
+	}
+
+	if info.Name == "" {
+		info.Name = js.Name()
+	}
+	if info.AxisCount == 0 {
+		info.AxisCount = js.AxisCount()
+	}
+	if info.ButtonCount == 0 {
+		info.ButtonCount = js.ButtonCount()
+	}
+
+	rec := &Recorder{w: bufio.NewWriter(w)}
+
+	// hold the recording until the header and initial state are written
+	rec.mutex.Lock()
+	defer rec.mutex.Unlock()
+
+	// subscribe before reading the state so no change is missed in between
+	events := er.Events(1024, Block)
+	go rec.run(events)
+
+	state, err := peekState(js)
+	if err == nil {
+		err = writeRecordHeader(rec.w, info)
+	}
+	for axis := 0; axis < info.AxisCount && err == nil; axis++ {
+		value := 0
+		if axis < len(state.AxisData) {
+			value = state.AxisData[axis]
+		}
+		err = writeRecord(rec.w, Event{Type: EventAxis, Number: axis, Value: value, Init: true})
+	}
+	for button := 0; button < info.ButtonCount && err == nil; button++ {
+		value := 0
+		if state.Pressed(button) {
+			value = 1
+		}
+		err = writeRecord(rec.w, Event{Type: EventButton, Number: button, Value: value, Init: true})
+	}
+	if err == nil {
+		err = rec.w.Flush()
+	}
+	if err != nil {
+		rec.stopped = true
+		return nil, err
+	}
+	return rec, nil
+}
+
+// run writes the events to the recording. It drains the channel until it is closed,
+// even after the recording stopped, so the joystick is never blocked
+func (rec *Recorder) run(events <-chan Event) {
+	for ev := range events {
+		rec.mutex.Lock()
+		if !rec.stopped && rec.err == nil {
+			if !rec.started {
+				rec.start = ev.Time
+				rec.started = true
+			}
+			// the driver timestamps wrap around, which the unsigned subtraction handles
+			ev.Time -= rec.start
+			rec.err = writeRecord(rec.w, ev)
+			// flush whenever we have caught up, so a recording is complete if the program dies
+			if rec.err == nil && len(events) == 0 {
+				rec.err = rec.w.Flush()
+			}
+		}
+		rec.mutex.Unlock()
+	}
+}
+
+// Close stops recording and flushes the recording to the writer. The joystick is not closed.
+// Returns the first error met while writing
+func (rec *Recorder) Close() error {
+	rec.mutex.Lock()
+	defer rec.mutex.Unlock()
+
+	if !rec.stopped {
+		rec.stopped = true
+		if rec.err == nil {
+			rec.err = rec.w.Flush()
+		}
+	}
+	return rec.err
+}
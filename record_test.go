@@ -0,0 +1,195 @@
+package joystick
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// timedJoystick is a VirtualJoystick whose events, with their timestamps, are sent by the test
+type timedJoystick struct {
+	*VirtualJoystick
+	events chan Event
+}
+
+func (js timedJoystick) Events(bufferSize int, policy OverflowPolicy) <-chan Event {
+	return js.events
+}
+
+func TestRecorder(t *testing.T) {
+	vj := NewVirtualJoystick("pad", 2, 2)
+	vj.Press(0)
+	vj.SetAxis(1, 1000)
+	// unbuffered, so each send returns once the recorder is done with the previous event
+	js := timedJoystick{vj, make(chan Event)}
+
+	var b bytes.Buffer
+	rec, err := NewRecorder(&b, js, DeviceInfo{Vendor: 0x045e, Product: 0x028e})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the initial state is recorded without consuming the press
+	state, err := vj.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.JustPressed(0) {
+		t.Error("press of button 0 consumed by NewRecorder")
+	}
+
+	// timestamps are kept relative to the first event, across the wrap around of the driver clock
+	events := []Event{
+		{Type: EventAxis, Number: 0, Value: -5000, Time: math.MaxUint32 - 99},
+		{Type: EventButton, Number: 1, Value: 1, Time: 150},
+		{Type: EventButton, Number: 0, Value: 0, Time: 900},
+	}
+	for _, ev := range events {
+		js.events <- ev
+	}
+	js.events <- Event{Type: EventAxis, Number: 1, Value: 0, Time: 1000}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rp, err := OpenReplay(&b, ReplayOptions{Step: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rp.Close()
+
+	if info := rp.Info(); info.Name != "pad" || info.Vendor != 0x045e || info.Product != 0x028e ||
+		info.AxisCount != 2 || info.ButtonCount != 2 {
+		t.Errorf("got %+v from the recording header", info)
+	}
+	state, err = rp.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(state.AxisData, []int{0, 1000}) || !reflect.DeepEqual(state.ButtonData, []bool{true, false}) {
+		t.Errorf("got initial axis %v and buttons %v", state.AxisData, state.ButtonData)
+	}
+
+	for i, time := range []uint32{0, 250, 1000} {
+		want := events[i]
+		want.Time = time
+		got, err := rp.Step()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("replayed %+v, want %+v", got, want)
+		}
+	}
+	if state, _ := rp.Read(); !reflect.DeepEqual(state.AxisData, []int{-5000, 1000}) || !reflect.DeepEqual(state.ButtonData, []bool{false, true}) {
+		t.Errorf("got axis %v and buttons %v after the replay", state.AxisData, state.ButtonData)
+	}
+}
+
+func TestRecorderNeedsEvents(t *testing.T) {
+	var b bytes.Buffer
+	if _, err := NewRecorder(&b, Filtered(NewVirtualJoystick("pad", 2, 2)), DeviceInfo{}); err == nil {
+		t.Error("expected an error recording a joystick without events")
+	}
+}
+
+// recording returns a recording of a joystick with 2 axis and 2 buttons, made of the events
+func recording(t *testing.T, events ...Event) *bytes.Buffer {
+	t.Helper()
+	var b bytes.Buffer
+	err := writeRecordHeader(&b, DeviceInfo{Name: "pad", AxisCount: 2, ButtonCount: 2})
+	for _, ev := range events {
+		if err == nil {
+			err = writeRecord(&b, ev)
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &b
+}
+
+func TestReplaySpeed(t *testing.T) {
+	events := []Event{
+		{Type: EventAxis, Number: 0, Value: 100, Time: 1000},
+		{Type: EventButton, Number: 1, Value: 1, Time: 1500},
+		{Type: EventAxis, Number: 1, Value: -200, Time: 1500},
+		{Type: EventAxis, Number: 0, Value: 300, Time: 3000},
+		{Type: EventButton, Number: 1, Value: 0, Time: 4000},
+	}
+	b := recording(t, append([]Event{{Type: EventAxis, Number: 0, Value: 50, Init: true}}, events...)...)
+
+	// 4 seconds of recording replayed in 40ms, the first event 10ms after opening
+	start := time.Now()
+	rp, err := OpenReplay(b, ReplayOptions{Speed: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rp.Close()
+	ch := rp.Events(len(events), Block)
+
+	for _, want := range events {
+		select {
+		case got := <-ch:
+			if got != want {
+				t.Errorf("replayed %+v, want %+v", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the replay")
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("replayed in %v, faster than the recording at speed 100", elapsed)
+	}
+
+	// the replay ends like a joystick that was unplugged
+	if _, ok := <-ch; ok {
+		t.Error("event channel not closed at the end of the replay")
+	}
+	state, err := rp.Read()
+	if !errors.Is(err, ErrDisconnected) {
+		t.Errorf("got %v at the end of the replay, want ErrDisconnected", err)
+	}
+	if !reflect.DeepEqual(state.AxisData, []int{300, -200}) || !reflect.DeepEqual(state.ButtonData, []bool{false, false}) {
+		t.Errorf("got axis %v and buttons %v at the end of the replay", state.AxisData, state.ButtonData)
+	}
+	if !state.JustPressed(1) || !state.JustReleased(1) {
+		t.Error("press and release of button 1 not seen")
+	}
+}
+
+func TestReplayClose(t *testing.T) {
+	// the second event is an hour into the recording
+	rp, err := OpenReplay(recording(t,
+		Event{Type: EventAxis, Number: 0, Value: 100},
+		Event{Type: EventAxis, Number: 0, Value: 200, Time: 3600 * 1000},
+	), ReplayOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := WaitFor(ctx, rp, func(s State) bool { return s.AxisData[0] == 100 }); err != nil {
+		t.Fatal(err)
+	}
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- rp.Close()
+	}()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close waited for the replay")
+	}
+	if state, err := rp.Read(); err != ErrClosed || state.AxisData[0] != 100 {
+		t.Errorf("got %v and axis %d after Close, want ErrClosed and 100", err, state.AxisData[0])
+	}
+}
@@ -0,0 +1,272 @@
+package joystick
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// ReplayOptions control how a recording is replayed
+type ReplayOptions struct {
+	// Speed of the replay: 1 replays in real time, 2 twice as fast. 0 means 1
+	Speed float64
+	// Step disables timing: events are only replayed by calls to Replay.Step
+	Step bool
+}
+
+// Replay is a Joystick that replays a recording made by a Recorder
+type Replay struct {
+	info      DeviceInfo
+	r         *bufio.Reader
+	speed     float64
+	step      bool
+	pending   *Event
+	stepMutex sync.Mutex
+	mutex     sync.Mutex
+	state     State
+	readerr   error
+	closed    bool
+	events    eventHub
+	changes   changeNotifier
+	done      chan struct{}
+	finished  chan struct{}
+	closeOnce sync.Once
+}
+
+// OpenReplay opens a recording made by a Recorder as a Joystick.
+// The initial state of the recording is applied straight away. Unless opts.Step is set,
+// the remaining events are then replayed in the background, keeping their original timing.
+// Once every event has been replayed Read returns ErrDisconnected, like a joystick that was unplugged
+func OpenReplay(r io.Reader, opts ReplayOptions) (*Replay, error) {
+	br := bufio.NewReader(r)
+	info, err := readRecordHeader(br)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recording header: %v", err)
+	}
+
+	rp := &Replay{
+		info:     info,
+		r:        br,
+		speed:    opts.Speed,
+		step:     opts.Step,
+		done:     make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+	if rp.speed <= 0 {
+		rp.speed = 1
+	}
+	rp.state.AxisData = make([]int, info.AxisCount)
+	rp.state.ButtonData = make([]bool, info.ButtonCount)
+
+	for {
+		ev, err := readRecord(br)
+		if err != nil {
+			rp.finish(err)
+			break
+		}
+		if !ev.Init {
+			rp.pending = &ev
+			break
+		}
+		rp.apply(ev)
+	}
+
+	if !rp.step && rp.readerr == nil {
+		go rp.run()
+	} else {
+		close(rp.finished)
+	}
+	return rp, nil
+}
+
+// Info returns the device information stored in the recording
+func (rp *Replay) Info() DeviceInfo {
+	return rp.info
+}
+
+func (rp *Replay) AxisCount() int {
+	return rp.info.AxisCount
+}
+
+func (rp *Replay) ButtonCount() int {
+	return rp.info.ButtonCount
+}
+
+func (rp *Replay) Name() string {
+	return rp.info.Name
+}
+
+func (rp *Replay) Read() (State, error) {
+	var state State
+	err := rp.ReadInto(&state)
+	return state, err
+}
+
+func (rp *Replay) ReadInto(state *State) error {
+	rp.mutex.Lock()
+	defer rp.mutex.Unlock()
+
+	return rp.snapshot(state)
+}
+
+func (rp *Replay) WaitForChange(ctx context.Context, since uint64) (State, error) {
+	var state State
+	err := rp.changes.waitFor(ctx, &rp.mutex, func() bool {
+		return rp.state.Sequence > since || rp.readerr != nil || rp.closed
+	})
+	if err != nil {
+		return state, err
+	}
+	defer rp.mutex.Unlock()
+
+	err = rp.snapshot(&state)
+	return state, err
+}
+
+// snapshot implements ReadInto. Must be called with the mutex held
+func (rp *Replay) snapshot(state *State) error {
+	rp.state.readInto(state)
+	if rp.closed {
+		return ErrClosed
+	}
+	return rp.readerr
+}
+
+// peekState implements statePeeker
+func (rp *Replay) peekState(state *State) error {
+	rp.mutex.Lock()
+	defer rp.mutex.Unlock()
+
+	rp.state.copyInto(state)
+	if rp.closed {
+		return ErrClosed
+	}
+	return rp.readerr
+}
+
+func (rp *Replay) Events(bufferSize int, policy OverflowPolicy) <-chan Event {
+	return rp.events.subscribe(bufferSize, policy)
+}
+
+// Step replays the next event and returns it. Returns io.EOF at the end of the recording.
+// Only available on replays opened with ReplayOptions.Step set
+func (rp *Replay) Step() (Event, error) {
+	if !rp.step {
+		return Event{}, errors.New("Replay is not in step mode")
+	}
+
+	rp.stepMutex.Lock()
+	defer rp.stepMutex.Unlock()
+
+	select {
+	case <-rp.done:
+		return Event{}, ErrClosed
+	default:
+	}
+
+	ev, err := rp.next()
+	if err != nil {
+		rp.finish(err)
+		return ev, err
+	}
+	rp.apply(ev)
+	rp.events.publish(ev)
+	return ev, nil
+}
+
+// Close stops the replay and waits for the background replay to exit
+func (rp *Replay) Close() error {
+	rp.closeOnce.Do(func() {
+		rp.mutex.Lock()
+		rp.closed = true
+		rp.mutex.Unlock()
+
+		close(rp.done)
+		rp.events.interrupt()
+		<-rp.finished
+
+		rp.stepMutex.Lock()
+		rp.finish(ErrClosed)
+		rp.stepMutex.Unlock()
+	})
+	return nil
+}
+
+// run replays the events with their recorded timing
+func (rp *Replay) run() {
+	defer close(rp.finished)
+
+	start := time.Now()
+	for {
+		select {
+		case <-rp.done:
+			return
+		default:
+		}
+
+		ev, err := rp.next()
+		if err != nil {
+			rp.finish(err)
+			return
+		}
+
+		at := start.Add(time.Duration(float64(time.Duration(ev.Time)*time.Millisecond) / rp.speed))
+		if wait := time.Until(at); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-rp.done:
+				return
+			}
+		}
+
+		rp.apply(ev)
+		rp.events.publish(ev)
+	}
+}
+
+// next returns the next event of the recording
+func (rp *Replay) next() (Event, error) {
+	if rp.pending != nil {
+		ev := *rp.pending
+		rp.pending = nil
+		return ev, nil
+	}
+	return readRecord(rp.r)
+}
+
+func (rp *Replay) apply(ev Event) {
+	rp.mutex.Lock()
+	defer rp.mutex.Unlock()
+
+	changed := false
+	switch {
+	case ev.Type == EventButton && ev.Init:
+		changed = rp.state.setButton(ev.Number, ev.Value != 0)
+	case ev.Type == EventButton:
+		changed = rp.state.updateButton(ev.Number, ev.Value != 0)
+	case ev.Type == EventAxis:
+		changed = rp.state.setAxis(ev.Number, ev.Value)
+	}
+	if changed {
+		rp.state.markChanged(time.Now())
+		rp.changes.notify()
+	}
+}
+
+// finish ends the replay, err being the reason
+func (rp *Replay) finish(err error) {
+	rp.mutex.Lock()
+	if rp.readerr == nil {
+		if err == io.EOF {
+			err = errors.New("end of recording")
+		}
+		rp.readerr = fmt.Errorf("%w: %v", ErrDisconnected, err)
+	}
+	rp.changes.notify()
+	rp.mutex.Unlock()
+	rp.events.close()
+}
@@ -0,0 +1,188 @@
+package joystick
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// VirtualJoystick is an in-memory Joystick whose state is set by the program,
+// for testing code that uses joysticks without a device attached.
+// It reports errors the way the real backends do, and implements StateReader, EventReader and ChangeWaiter
+type VirtualJoystick struct {
+	name  string
+	start time.Time
+	// held while changing the state, so events are published in order
+	writeMutex sync.Mutex
+	mutex      sync.Mutex
+	state      State
+	readerr    error
+	closed     bool
+	events     eventHub
+	changes    changeNotifier
+}
+
+// NewVirtualJoystick returns a VirtualJoystick with every axis centered and every button released
+func NewVirtualJoystick(name string, axisCount, buttonCount int) *VirtualJoystick {
+	if axisCount < 0 {
+		axisCount = 0
+	}
+	if buttonCount < 0 {
+		buttonCount = 0
+	}
+
+	vj := &VirtualJoystick{name: name, start: time.Now()}
+	vj.state.AxisData = make([]int, axisCount)
+	vj.state.ButtonData = make([]bool, buttonCount)
+	return vj
+}
+
+func (vj *VirtualJoystick) AxisCount() int {
+	return len(vj.state.AxisData)
+}
+
+func (vj *VirtualJoystick) ButtonCount() int {
+	return len(vj.state.ButtonData)
+}
+
+func (vj *VirtualJoystick) Name() string {
+	return vj.name
+}
+
+func (vj *VirtualJoystick) Read() (State, error) {
+	var state State
+	err := vj.ReadInto(&state)
+	return state, err
+}
+
+func (vj *VirtualJoystick) ReadInto(state *State) error {
+	vj.mutex.Lock()
+	defer vj.mutex.Unlock()
+
+	return vj.snapshot(state)
+}
+
+func (vj *VirtualJoystick) WaitForChange(ctx context.Context, since uint64) (State, error) {
+	var state State
+	err := vj.changes.waitFor(ctx, &vj.mutex, func() bool {
+		return vj.state.Sequence > since || vj.readerr != nil
+	})
+	if err != nil {
+		return state, err
+	}
+	defer vj.mutex.Unlock()
+
+	err = vj.snapshot(&state)
+	return state, err
+}
+
+// snapshot implements ReadInto. Must be called with the mutex held
+func (vj *VirtualJoystick) snapshot(state *State) error {
+	vj.state.readInto(state)
+	if vj.closed {
+		return ErrClosed
+	}
+	return vj.readerr
+}
+
+// peekState implements statePeeker
+func (vj *VirtualJoystick) peekState(state *State) error {
+	vj.mutex.Lock()
+	defer vj.mutex.Unlock()
+
+	vj.state.copyInto(state)
+	if vj.closed {
+		return ErrClosed
+	}
+	return vj.readerr
+}
+
+func (vj *VirtualJoystick) Events(bufferSize int, policy OverflowPolicy) <-chan Event {
+	return vj.events.subscribe(bufferSize, policy)
+}
+
+// SetAxis sets the value of an axis. The value is clamped to -32767..32767.
+// Axis that do not exist are ignored, as are changes after Disconnect or Close
+func (vj *VirtualJoystick) SetAxis(axis int, value int) {
+	if axis < 0 || axis >= vj.AxisCount() {
+		return
+	}
+	vj.update(Event{Type: EventAxis, Number: axis, Value: clampAxis(value)})
+}
+
+// Press presses a button. Buttons that do not exist are ignored, as are changes after Disconnect or Close
+func (vj *VirtualJoystick) Press(button int) {
+	vj.setButton(button, 1)
+}
+
+// Release releases a button. Buttons that do not exist are ignored, as are changes after Disconnect or Close
+func (vj *VirtualJoystick) Release(button int) {
+	vj.setButton(button, 0)
+}
+
+func (vj *VirtualJoystick) setButton(button int, value int) {
+	if button < 0 || button >= vj.ButtonCount() {
+		return
+	}
+	vj.update(Event{Type: EventButton, Number: button, Value: value})
+}
+
+// update applies ev to the state, and publishes it if it changed the state
+func (vj *VirtualJoystick) update(ev Event) {
+	vj.writeMutex.Lock()
+	defer vj.writeMutex.Unlock()
+
+	vj.mutex.Lock()
+	if vj.readerr != nil {
+		vj.mutex.Unlock()
+		return
+	}
+	changed := false
+	switch ev.Type {
+	case EventButton:
+		changed = vj.state.updateButton(ev.Number, ev.Value != 0)
+	case EventAxis:
+		changed = vj.state.setAxis(ev.Number, ev.Value)
+	}
+	now := time.Now()
+	if changed {
+		vj.state.markChanged(now)
+		vj.changes.notify()
+	}
+	vj.mutex.Unlock()
+
+	if changed {
+		ev.Time = uint32(now.Sub(vj.start) / time.Millisecond)
+		vj.events.publish(ev)
+	}
+}
+
+// Disconnect simulates the device being unplugged: Read returns the last state with
+// an error wrapping ErrDisconnected, and the channels returned by Events are closed
+func (vj *VirtualJoystick) Disconnect() {
+	vj.stop(fmt.Errorf("%w: virtual joystick %q unplugged", ErrDisconnected, vj.name))
+}
+
+func (vj *VirtualJoystick) Close() error {
+	vj.mutex.Lock()
+	vj.closed = true
+	vj.mutex.Unlock()
+
+	vj.events.interrupt()
+	vj.stop(ErrClosed)
+	return nil
+}
+
+func (vj *VirtualJoystick) stop(err error) {
+	vj.writeMutex.Lock()
+	defer vj.writeMutex.Unlock()
+
+	vj.mutex.Lock()
+	if vj.readerr == nil {
+		vj.readerr = err
+	}
+	vj.changes.notify()
+	vj.mutex.Unlock()
+	vj.events.close()
+}
@@ -0,0 +1,103 @@
+package joystick
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestVirtualJoystick(t *testing.T) {
+	vj := NewVirtualJoystick("pad", 2, 3)
+	if vj.AxisCount() != 2 || vj.ButtonCount() != 3 || vj.Name() != "pad" {
+		t.Fatalf("got %d axis, %d buttons, name %q", vj.AxisCount(), vj.ButtonCount(), vj.Name())
+	}
+
+	vj.SetAxis(0, 40000)
+	vj.SetAxis(1, -40000)
+	vj.Press(2)
+	// inputs that do not exist are ignored
+	vj.SetAxis(2, 100)
+	vj.Press(-1)
+	vj.Press(3)
+
+	state, err := vj.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(state.AxisData, []int{MaxAxisValue, -MaxAxisValue}) {
+		t.Errorf("got axis %v, want clamped to %d", state.AxisData, MaxAxisValue)
+	}
+	if !reflect.DeepEqual(state.ButtonData, []bool{false, false, true}) || !state.JustPressed(2) {
+		t.Errorf("got buttons %v", state.ButtonData)
+	}
+	// edges are cleared by the read
+	if state, _ = vj.Read(); state.JustPressed(2) {
+		t.Error("press seen by two reads")
+	}
+}
+
+func TestVirtualJoystickDisconnect(t *testing.T) {
+	vj := NewVirtualJoystick("pad", 1, 1)
+	events := vj.Events(4, DropNewest)
+	vj.SetAxis(0, 100)
+	vj.Disconnect()
+
+	// the last state is returned with the error, and later changes are ignored
+	vj.SetAxis(0, 200)
+	state, err := vj.Read()
+	if !errors.Is(err, ErrDisconnected) || state.AxisData[0] != 100 {
+		t.Errorf("got %v and axis %d after Disconnect, want ErrDisconnected and 100", err, state.AxisData[0])
+	}
+	if got := drain(events); !reflect.DeepEqual(got, []int{100}) {
+		t.Errorf("got events %v, want [100]", got)
+	}
+	if _, ok := <-events; ok {
+		t.Error("event channel not closed by Disconnect")
+	}
+	if _, ok := <-vj.Events(4, DropNewest); ok {
+		t.Error("event channel subscribed after Disconnect not closed")
+	}
+
+	// Close takes priority over the disconnection
+	if err := vj.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := vj.Read(); err != ErrClosed {
+		t.Errorf("got %v after Close, want ErrClosed", err)
+	}
+}
+
+func TestVirtualJoystickWaitForChange(t *testing.T) {
+	vj := NewVirtualJoystick("pad", 1, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	vj.SetAxis(0, 100)
+	state, err := vj.WaitForChange(ctx, 0)
+	if err != nil || state.AxisData[0] != 100 {
+		t.Fatalf("got %v and axis %d, want axis 100", err, state.AxisData[0])
+	}
+
+	// a pending wait is woken by Disconnect
+	done := make(chan error, 1)
+	go func() {
+		_, err := vj.WaitForChange(ctx, state.Sequence)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("WaitForChange returned %v without a change", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	vj.Disconnect()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrDisconnected) {
+			t.Errorf("got %v, want ErrDisconnected", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("WaitForChange not woken by Disconnect")
+	}
+}
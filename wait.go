@@ -0,0 +1,90 @@
+package joystick
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// interval between reads when waiting on a joystick that does not implement ChangeWaiter
+const waitPollInterval = 10 * time.Millisecond
+
+// Interface ChangeWaiter is implemented by joysticks that can block until their state changes,
+// without polling
+type ChangeWaiter interface {
+	// WaitForChange waits until the Sequence of the state is greater than since, then returns
+	// the state like Read does. Returns early with the error if the joystick is disconnected
+	// or closed, or if ctx is done
+	WaitForChange(ctx context.Context, since uint64) (State, error)
+}
+
+// WaitForChange waits until the Sequence of the state of js is greater than since, and returns
+// the state. Pass the Sequence of the last State seen. Joysticks that do not implement
+// ChangeWaiter are polled
+func WaitForChange(ctx context.Context, js Joystick, since uint64) (State, error) {
+	if cw, ok := js.(ChangeWaiter); ok {
+		return cw.WaitForChange(ctx, since)
+	}
+
+	ticker := time.NewTicker(waitPollInterval)
+	defer ticker.Stop()
+
+	var state State
+	for {
+		if err := ReadInto(js, &state); err != nil || state.Sequence > since {
+			return state, err
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return state, ctx.Err()
+		}
+	}
+}
+
+// WaitFor waits until the state of js satisfies cond, for example:
+//   state, err := joystick.WaitFor(ctx, js, func(s joystick.State) bool {
+//     return s.JustPressed(0)
+//   })
+// cond is called with the current state, then with the state after every change
+func WaitFor(ctx context.Context, js Joystick, cond func(State) bool) (State, error) {
+	state, err := js.Read()
+	for err == nil && !cond(state) {
+		state, err = WaitForChange(ctx, js, state.Sequence)
+	}
+	return state, err
+}
+
+// changeNotifier wakes up the goroutines waiting for a change to a state guarded by a mutex
+type changeNotifier struct {
+	ch chan struct{}
+}
+
+// notify wakes up every waiting goroutine. Must be called with the mutex held
+func (n *changeNotifier) notify() {
+	if n.ch != nil {
+		close(n.ch)
+		n.ch = nil
+	}
+}
+
+// waitFor locks mu and waits until ready, called with mu held, returns true.
+// Returns nil with mu still locked, or ctx.Err() with mu unlocked if ctx is done first
+func (n *changeNotifier) waitFor(ctx context.Context, mu sync.Locker, ready func() bool) error {
+	mu.Lock()
+	for !ready() {
+		if n.ch == nil {
+			n.ch = make(chan struct{})
+		}
+		ch := n.ch
+		mu.Unlock()
+
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		mu.Lock()
+	}
+	return nil
+}
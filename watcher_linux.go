@@ -0,0 +1,151 @@
+// +build linux
+
+package joystick
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Watcher reports joysticks being connected and disconnected
+type Watcher struct {
+	// Events receives a DeviceEvent each time a joystick is connected or disconnected.
+	// It is closed when the Watcher is closed
+	Events <-chan DeviceEvent
+
+	file      *os.File
+	events    chan DeviceEvent
+	devices   map[int]DeviceInfo
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// NewWatcher starts watching for joysticks being connected and disconnected.
+//
+// Under linux /dev/input is watched with inotify. A DeviceAdded event is sent
+// straight away for each joystick already connected.
+func NewWatcher() (*Watcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_NONBLOCK | unix.IN_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = unix.InotifyAddWatch(fd, devInputRoot,
+		unix.IN_CREATE|unix.IN_DELETE|unix.IN_MOVED_TO|unix.IN_MOVED_FROM)
+	if err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+
+	// the watch is added before enumerating so no device can be missed
+	current, err := Enumerate()
+	if err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+
+	w := &Watcher{
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		events:  make(chan DeviceEvent, len(current)+16),
+		devices: make(map[int]DeviceInfo),
+		done:    make(chan struct{}),
+	}
+	w.Events = w.events
+
+	for _, info := range current {
+		w.devices[info.ID] = info
+		w.events <- DeviceEvent{DeviceAdded, info}
+	}
+
+	w.wg.Add(1)
+	go w.run()
+
+	return w, nil
+}
+
+// Close stops the Watcher and waits for it to finish
+func (w *Watcher) Close() error {
+	var err error
+	w.closeOnce.Do(func() {
+		close(w.done)
+		err = w.file.Close()
+		w.wg.Wait()
+	})
+	return err
+}
+
+func (w *Watcher) run() {
+	defer w.wg.Done()
+	defer close(w.events)
+
+	var buffer [4096]byte
+
+	for {
+		n, err := w.file.Read(buffer[:])
+		if err != nil {
+			return
+		}
+
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			raw := (*unix.InotifyEvent)(unsafe.Pointer(&buffer[offset]))
+			nameStart := offset + unix.SizeofInotifyEvent
+			nameEnd := nameStart + int(raw.Len)
+			if nameEnd > n {
+				break
+			}
+			name := string(bytes.TrimRight(buffer[nameStart:nameEnd], "\x00"))
+			offset = nameEnd
+
+			if raw.Mask&unix.IN_IGNORED != 0 {
+				// the watched directory has gone
+				return
+			}
+
+			ev, ok := w.deviceEvent(raw.Mask, name)
+			if !ok {
+				continue
+			}
+
+			select {
+			case w.events <- ev:
+			case <-w.done:
+				return
+			}
+		}
+	}
+}
+
+// deviceEvent returns the event to report for an inotify event on the device node name.
+// ok is false if there is nothing to report
+func (w *Watcher) deviceEvent(mask uint32, name string) (ev DeviceEvent, ok bool) {
+	id, ok := parseNodeID(name, "js")
+	if !ok {
+		return ev, false
+	}
+
+	switch {
+	case mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0:
+		if _, known := w.devices[id]; known {
+			// created between adding the watch and enumerating, already reported
+			return ev, false
+		}
+		ev = DeviceEvent{DeviceAdded, deviceInfo(id)}
+		w.devices[id] = ev.Info
+	case mask&(unix.IN_DELETE|unix.IN_MOVED_FROM) != 0:
+		info, known := w.devices[id]
+		if !known {
+			info = DeviceInfo{ID: id, Path: filepath.Join(devInputRoot, name)}
+		}
+		delete(w.devices, id)
+		ev = DeviceEvent{DeviceRemoved, info}
+	default:
+		return ev, false
+	}
+	return ev, true
+}
@@ -0,0 +1,98 @@
+// +build linux
+
+package joystick
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func nextDeviceEvent(t *testing.T, w *Watcher) DeviceEvent {
+	select {
+	case ev, ok := <-w.Events:
+		if !ok {
+			t.Fatal("Events closed")
+		}
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a device event")
+	}
+	return DeviceEvent{}
+}
+
+func TestWatcher(t *testing.T) {
+	dev, sys := fakeInputTree(t)
+	writeFakeFiles(t, dev, map[string]string{"js0": ""})
+	writeFakeFiles(t, sys, map[string]string{
+		"js0/device/name": "First Pad\n",
+		"js1/device/name": "Second Pad\n",
+	})
+
+	w, err := NewWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if ev := nextDeviceEvent(t, w); ev.Type != DeviceAdded || ev.Info.ID != 0 || ev.Info.Name != "First Pad" {
+		t.Errorf("got %+v, want js0 added", ev)
+	}
+
+	// other device nodes are ignored
+	writeFakeFiles(t, dev, map[string]string{"event4": "", "js1": ""})
+	if ev := nextDeviceEvent(t, w); ev.Type != DeviceAdded || ev.Info.ID != 1 || ev.Info.Name != "Second Pad" {
+		t.Errorf("got %+v, want js1 added", ev)
+	}
+
+	// the sysfs entry has gone by the time the node is removed, the information
+	// collected when the device was added is reported
+	if err := os.RemoveAll(filepath.Join(sys, "js0")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dev, "js0")); err != nil {
+		t.Fatal(err)
+	}
+	if ev := nextDeviceEvent(t, w); ev.Type != DeviceRemoved || ev.Info.ID != 0 || ev.Info.Name != "First Pad" {
+		t.Errorf("got %+v, want js0 removed", ev)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-w.Events; ok {
+		t.Error("Events not closed by Close")
+	}
+}
+
+func TestWatcherDeviceEvent(t *testing.T) {
+	dev, _ := fakeInputTree(t)
+	w := &Watcher{devices: map[int]DeviceInfo{
+		3: {ID: 3, Name: "Known Pad"},
+	}}
+
+	// a node created between adding the watch and enumerating is reported once
+	if ev, ok := w.deviceEvent(unix.IN_CREATE, "js3"); ok {
+		t.Errorf("got %+v for a device already reported", ev)
+	}
+
+	if ev, ok := w.deviceEvent(unix.IN_DELETE, "js3"); !ok || ev.Type != DeviceRemoved || ev.Info.Name != "Known Pad" {
+		t.Errorf("got %+v, %v, want js3 removed", ev, ok)
+	}
+	if ev, ok := w.deviceEvent(unix.IN_MOVED_TO, "js3"); !ok || ev.Type != DeviceAdded || ev.Info.ID != 3 {
+		t.Errorf("got %+v, %v, want js3 added again", ev, ok)
+	}
+
+	// removal of a device that was never reported
+	ev, ok := w.deviceEvent(unix.IN_MOVED_FROM, "js7")
+	if want := filepath.Join(dev, "js7"); !ok || ev.Type != DeviceRemoved || ev.Info.ID != 7 || ev.Info.Path != want {
+		t.Errorf("got %+v, %v, want js7 removed", ev, ok)
+	}
+
+	if ev, ok := w.deviceEvent(unix.IN_CREATE, "event7"); ok {
+		t.Errorf("got %+v for an evdev node", ev)
+	}
+}
@@ -0,0 +1,25 @@
+// +build !linux
+
+package joystick
+
+import (
+	"errors"
+)
+
+// Watcher reports joysticks being connected and disconnected
+type Watcher struct {
+	// Events receives a DeviceEvent each time a joystick is connected or disconnected.
+	// It is closed when the Watcher is closed
+	Events <-chan DeviceEvent
+}
+
+// NewWatcher starts watching for joysticks being connected and disconnected.
+// It is only supported under linux
+func NewWatcher() (*Watcher, error) {
+	return nil, errors.New("Watcher is only supported on linux")
+}
+
+// Close stops the Watcher and waits for it to finish
+func (w *Watcher) Close() error {
+	return nil
+}